@@ -1,7 +1,10 @@
+import { refreshSession } from '@/services/session-service';
 import { ref, type Ref } from 'vue';
 
 export type User = {
   jwt: string;
+  refreshToken?: string;
+  expiresIn?: number;
   isAdmin?: boolean;
   gameId?: string;
 }
@@ -10,6 +13,11 @@ let jwt: Ref<string> | undefined;
 let isAdmin: Ref<boolean> | undefined;
 let gameId: Ref<string | null> | undefined;
 
+// Access tokens are refreshed this long before they expire
+const refreshMargin = 60 * 1000;
+const refreshCheckInterval = 30 * 1000;
+const refreshLockName = 'rating-party-refresh';
+
 export function useSession() {
   if (!jwt) {
     jwt = ref(localStorage.getItem('jwt') ?? '');
@@ -32,16 +40,65 @@ export function useSession() {
       isAdmin!.value = user.isAdmin ?? false;
       gameId!.value = user.gameId ?? '';
       localStorage.setItem('jwt', user.jwt);
+      if (user.refreshToken) {
+        localStorage.setItem('refreshToken', user.refreshToken);
+      }
+      if (user.expiresIn) {
+        localStorage.setItem('expiresAt', String(Date.now() + user.expiresIn * 1000));
+      }
       localStorage.setItem('isAdmin', String(user.isAdmin ?? false));
       if (user.gameId) {
         localStorage.setItem('gameId', user.gameId);
       }
     },
+    // refreshIfNeeded swaps the refresh token for a new access token when the current one is about to expire.
+    // Every tab shares the refresh token, so tabs take turns and a tab that was beaten to it picks up the new
+    // tokens instead of presenting the used refresh token, which the server treats as theft.
+    async refreshIfNeeded(): Promise<void> {
+      if (!navigator.locks) {
+        return this.refreshNow();
+      }
+      await navigator.locks.request(refreshLockName, () => this.refreshNow());
+    },
+    async refreshNow(): Promise<void> {
+      this.syncFromStorage();
+      const refreshToken = localStorage.getItem('refreshToken');
+      const expiresAt = Number(localStorage.getItem('expiresAt') ?? 0);
+      if (!jwt?.value || !refreshToken || expiresAt - Date.now() > refreshMargin) return;
+      try {
+        const res = await refreshSession(refreshToken);
+        if (res === false) {
+          this.deleteUser();
+          return;
+        }
+        this.setUser(res);
+      } catch (err) {
+        console.error(err);
+      }
+    },
+    // syncFromStorage picks up tokens another tab refreshed
+    syncFromStorage() {
+      const storedJwt = localStorage.getItem('jwt') ?? '';
+      if (jwt && storedJwt !== jwt.value) {
+        jwt.value = storedJwt;
+      }
+    },
+    keepFresh() {
+      setInterval(() => this.refreshIfNeeded(), refreshCheckInterval);
+      window.addEventListener('storage', (event) => {
+        if (event.key === 'jwt') {
+          this.syncFromStorage();
+        }
+      });
+      return this.refreshIfNeeded();
+    },
     deleteUser() {
       jwt!.value = '';
       isAdmin!.value = false;
       gameId!.value = null;
       localStorage.removeItem('jwt');
+      localStorage.removeItem('refreshToken');
+      localStorage.removeItem('expiresAt');
       localStorage.removeItem('isAdmin');
       localStorage.removeItem('gameId');
     },
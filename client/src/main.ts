@@ -2,6 +2,7 @@ import './assets/base-styles.css';
 
 import { createApp } from 'vue';
 import App from './App.vue';
+import { useSession } from './composables/session';
 import router from './router';
 
 // Vuetify
@@ -21,4 +22,5 @@ app.use(router);
 
 app.use(vuetify);
 
-app.mount('#app');
+// Make sure a stored session has a usable access token before any view makes requests
+useSession().keepFresh().finally(() => app.mount('#app'));
@@ -6,6 +6,22 @@ export type Game = {
   gameName: string
   isRunning: boolean
   areResultsShared: boolean
+  rankingStrategy: string
+  tieBreakers: string[]
+  mergeDuplicates: boolean
+  codeDisabled: boolean
+  disableCodeWhenStopped: boolean
+  codeExpiresAt: string | null
+  maxJoins: number
+  joinCount: number
+  deletedAt?: string
+}
+
+export type JoinSettings = {
+  codeDisabled: boolean
+  disableCodeWhenStopped: boolean
+  codeExpiresAt: string | null
+  maxJoins: number
 }
 
 export async function getAllGames(jwt: string): Promise<Game[] | false> {
@@ -74,4 +90,68 @@ export async function deleteGame(jwt: string, gameId: string): Promise<Game> {
   });
   const game: Game = await response.json();
   return game;
-}
\ No newline at end of file
+}
+
+export async function updateGameRanking(jwt: string, gameId: string, rankingStrategy: string, tieBreakers: string[], mergeDuplicates?: boolean): Promise<void> {
+  await fetch(`${baseUrl}/games/${gameId}/ranking`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+    body: JSON.stringify({
+      rankingStrategy,
+      tieBreakers,
+      mergeDuplicates,
+    }),
+  });
+}
+
+export async function getDeletedGames(jwt: string): Promise<Game[] | false> {
+  const response = await fetch(`${baseUrl}/games?deleted=true`, {
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  if (!response.ok) {
+    return false;
+  }
+  const games: Game[] = await response.json();
+  return games;
+}
+
+export async function restoreGame(jwt: string, gameId: string): Promise<Game> {
+  const response = await fetch(`${baseUrl}/games/${gameId}/restore`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  const game: Game = await response.json();
+  return game;
+}
+
+export async function regenerateGameCode(jwt: string, gameId: string): Promise<Game> {
+  const response = await fetch(`${baseUrl}/games/${gameId}/code`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  const game: Game = await response.json();
+  return game;
+}
+
+export async function updateJoinSettings(jwt: string, gameId: string, settings: JoinSettings): Promise<void> {
+  await fetch(`${baseUrl}/games/${gameId}/code`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+    body: JSON.stringify(settings),
+  });
+}
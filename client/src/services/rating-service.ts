@@ -11,6 +11,14 @@ export type Rating = {
   tasteRating: number
   overallRating: number
   comments: string
+  hostEntered?: boolean
+}
+
+export type RatingsResult = {
+  rankingStrategy: string
+  tieBreakers: string[]
+  mergeDuplicates: boolean
+  rows: Record<string, unknown>[]
 }
 
 export async function getAllRatings(jwt: string, gameId: string): Promise<Rating[] | false> {
@@ -38,7 +46,7 @@ export async function putRating(jwt: string, rating: Rating): Promise<void> {
   });
 }
 
-export async function getResults(jwt: string, gameId: string): Promise<Record<string, unknown>[] | false> {
+export async function getResults(jwt: string, gameId: string): Promise<RatingsResult | false> {
   const response = await fetch(`${baseUrl}/games/${gameId}/ratings/results`, {
     headers: {
       'Content-Type': 'application/json',
@@ -48,6 +56,6 @@ export async function getResults(jwt: string, gameId: string): Promise<Record<st
   if (!response.ok) {
     return false;
   }
-  const results: Record<string, unknown>[] = await response.json();
+  const results: RatingsResult = await response.json();
   return results;
 }
\ No newline at end of file
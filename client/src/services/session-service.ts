@@ -2,8 +2,11 @@ import { baseUrl } from './utils';
 
 type SessionResponse = {
   jwt: string
+  refreshToken?: string
+  expiresIn?: number
   isAdmin?: boolean
   gameId?: string
+  retryAfter?: number
 }
 
 export async function signin(username: string, passcode: string): Promise<SessionResponse> {
@@ -14,6 +17,165 @@ export async function signin(username: string, passcode: string): Promise<Sessio
     },
     body: JSON.stringify({ username, passcode }),
   });
+  if (response.status === 429) {
+    return { jwt: '', retryAfter: Number(response.headers.get('Retry-After')) || 1 };
+  }
   const session: SessionResponse = await response.json();
   return session;
 }
+
+export async function refreshSession(refreshToken: string): Promise<SessionResponse | false> {
+  const response = await fetch(`${baseUrl}/token/refresh`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ refreshToken }),
+  });
+  if (!response.ok) {
+    return false;
+  }
+  const session: SessionResponse = await response.json();
+  return session;
+}
+
+// signout revokes the token on the server, failures are ignored because the token is discarded anyway
+export async function signout(jwt: string): Promise<void> {
+  try {
+    await fetch(`${baseUrl}/signout`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'Authorization': `Bearer ${jwt}`,
+      },
+    });
+  } catch (err) {
+    console.error(err);
+  }
+}
+
+export async function signinWithInvite(invite: string, username: string): Promise<SessionResponse> {
+  const response = await fetch(`${baseUrl}/signin/invite`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ invite, username }),
+  });
+  if (response.status === 429) {
+    return { jwt: '', retryAfter: Number(response.headers.get('Retry-After')) || 1 };
+  }
+  const session: SessionResponse = await response.json();
+  return session;
+}
+
+export type Invite = {
+  token: string
+  url: string
+  gameId: string
+  username?: string
+  expiresAt: string
+}
+
+export async function createInvite(jwt: string, gameId: string, username = '', validForHours = 0): Promise<Invite> {
+  const response = await fetch(`${baseUrl}/games/${gameId}/invites`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+    body: JSON.stringify({ username, validForHours }),
+  });
+  const invite: Invite = await response.json();
+  return invite;
+}
+
+export async function getInviteQRCode(jwt: string, gameId: string, format: 'png' | 'svg' = 'png', username = ''): Promise<Blob | false> {
+  const query = new URLSearchParams({ format });
+  if (username) {
+    query.set('username', username);
+  }
+  const response = await fetch(`${baseUrl}/games/${gameId}/invites/qr?${query}`, {
+    headers: {
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  if (!response.ok) {
+    return false;
+  }
+  return response.blob();
+}
+
+export type Lockout = {
+  kind: 'ip' | 'username' | 'usernameDelay'
+  value: string
+  failures: number
+  lockedUntil: string
+}
+
+export async function getLockouts(jwt: string): Promise<Lockout[] | false> {
+  const response = await fetch(`${baseUrl}/signin/lockouts`, {
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  if (!response.ok) {
+    return false;
+  }
+  const lockouts: Lockout[] = await response.json();
+  return lockouts;
+}
+
+export async function clearLockout(jwt: string, lockout: Lockout): Promise<void> {
+  await fetch(`${baseUrl}/signin/lockouts/${lockout.kind}/${encodeURIComponent(lockout.value)}`, {
+    method: 'DELETE',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+}
+
+export type Session = {
+  sessionId: string
+  isAdmin: boolean
+  gameId?: string
+  participantId?: string
+  username?: string
+  ipAddress: string
+  userAgent: string
+  createdAt: string
+  expiresAt: string
+}
+
+export async function getActiveSessions(jwt: string, filter: { gameId?: string, admin?: boolean } = {}): Promise<Session[] | false> {
+  const query = new URLSearchParams();
+  if (filter.gameId) {
+    query.set('gameId', filter.gameId);
+  }
+  if (filter.admin) {
+    query.set('admin', 'true');
+  }
+  const response = await fetch(`${baseUrl}/sessions?${query}`, {
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  if (!response.ok) {
+    return false;
+  }
+  const sessions: Session[] = await response.json();
+  return sessions;
+}
+
+export async function revokeSession(jwt: string, sessionId: string): Promise<void> {
+  await fetch(`${baseUrl}/sessions/${sessionId}`, {
+    method: 'DELETE',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+}
@@ -0,0 +1,92 @@
+import type { Game } from './game-service';
+import { baseUrl } from './utils';
+
+export type TemplateWine = {
+  templateWineId: string
+  wineName: string
+  wineCode: string
+  wineYear: number
+  duplicateOf?: string
+}
+
+export type Template = {
+  templateId: string
+  templateName: string
+  rankingStrategy: string
+  tieBreakers: string[]
+  mergeDuplicates: boolean
+  codeDisabled: boolean
+  disableCodeWhenStopped: boolean
+  maxJoins: number
+  wines: TemplateWine[]
+}
+
+export type CopyOptions = {
+  includeWines: boolean
+  includeWineNames: boolean
+}
+
+export async function getAllTemplates(jwt: string): Promise<Template[] | false> {
+  const response = await fetch(`${baseUrl}/templates`, {
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  if (!response.ok) {
+    return false;
+  }
+  const templates: Template[] = await response.json();
+  return templates;
+}
+
+export async function cloneGame(jwt: string, gameId: string, gameName: string, options: CopyOptions): Promise<Game> {
+  const response = await fetch(`${baseUrl}/games/${gameId}/clone`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+    body: JSON.stringify({ gameName, ...options }),
+  });
+  const game: Game = await response.json();
+  return game;
+}
+
+export async function saveTemplate(jwt: string, gameId: string, templateName: string, options: CopyOptions): Promise<Template> {
+  const response = await fetch(`${baseUrl}/games/${gameId}/template`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+    body: JSON.stringify({ templateName, ...options }),
+  });
+  const template: Template = await response.json();
+  return template;
+}
+
+export async function createGameFromTemplate(jwt: string, templateId: string, gameName: string): Promise<Game> {
+  const response = await fetch(`${baseUrl}/templates/${templateId}/games`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+    body: JSON.stringify({ gameName }),
+  });
+  const game: Game = await response.json();
+  return game;
+}
+
+export async function deleteTemplate(jwt: string, templateId: string): Promise<Template> {
+  const response = await fetch(`${baseUrl}/templates/${templateId}`, {
+    method: 'DELETE',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  const template: Template = await response.json();
+  return template;
+}
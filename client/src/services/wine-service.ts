@@ -5,6 +5,8 @@ export type Wine = {
   wineName: string
   wineCode: string
   wineYear: number
+  duplicateOfWineId?: string
+  deletedAt?: string
 }
 
 export async function getAllWines(jwt: string, gameId: string): Promise<Wine[] | false> {
@@ -48,4 +50,30 @@ export async function deleteWine(jwt: string, gameId: string, wineId: string): P
   });
   const wine = await response.json();
   return wine;
-}
\ No newline at end of file
+}
+
+export async function getDeletedWines(jwt: string, gameId: string): Promise<Wine[] | false> {
+  const response = await fetch(`${baseUrl}/games/${gameId}/wines?deleted=true`, {
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  if (!response.ok) {
+    return false;
+  }
+  const wines: Wine[] = await response.json();
+  return wines;
+}
+
+export async function restoreWine(jwt: string, gameId: string, wineId: string): Promise<Wine> {
+  const response = await fetch(`${baseUrl}/games/${gameId}/wines/${wineId}/restore`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${jwt}`,
+    },
+  });
+  const wine: Wine = await response.json();
+  return wine;
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/archive"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/jwtkeys"
+)
+
+const usage = `usage: server [-config file] [command]
+
+  server                                    start the server
+  server archive export <gameId> [file]     write a game archive to file or stdout
+  server archive import <file>              recreate a game from an archive, use - for stdin
+  server keys generate <EdDSA|ES256> <dir>  write a new signing key to the keys directory
+  server keys list                          list the configured keys, marking the signing key
+  server config print                       show the effective config with secrets redacted
+
+The config file is YAML or TOML and can also be given with CONFIG_FILE. Environment variables
+override the file, which overrides the defaults.`
+
+// runCommand runs a command line tool instead of the server
+func runCommand(args []string) error {
+	if len(args) >= 2 && args[0] == "archive" {
+		switch args[1] {
+		case "export":
+			if len(args) == 3 {
+				return exportArchive(args[2], "")
+			}
+			if len(args) == 4 {
+				return exportArchive(args[2], args[3])
+			}
+		case "import":
+			if len(args) == 3 {
+				return importArchive(args[2])
+			}
+		}
+	}
+	if len(args) == 4 && args[0] == "keys" && args[1] == "generate" {
+		return generateKey(args[2], args[3])
+	}
+	if len(args) == 2 && args[0] == "keys" && args[1] == "list" {
+		return listKeys()
+	}
+	if len(args) == 2 && args[0] == "config" && args[1] == "print" {
+		return printConfig()
+	}
+	return fmt.Errorf("unknown command\n%s", usage)
+}
+
+func newArchiveController() (*archive.Controller, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+	db, err := db.New(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return archive.NewController(cfg, db, game.NewController(cfg, db)), nil
+}
+
+// exportArchive writes the archive to the file, or to stdout when no file is given
+func exportArchive(gameID, file string) error {
+	controller, err := newArchiveController()
+	if err != nil {
+		return err
+	}
+	gameArchive, err := controller.Export(context.Background(), gameID)
+	if err != nil {
+		return err
+	}
+	out := io.Writer(os.Stdout)
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("failed to create archive file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(gameArchive); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+func importArchive(file string) error {
+	in := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open archive file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	var gameArchive archive.Archive
+	if err := json.NewDecoder(in).Decode(&gameArchive); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	controller, err := newArchiveController()
+	if err != nil {
+		return err
+	}
+	imported, err := controller.Import(context.Background(), &gameArchive)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %q as game %s with code %s\n", imported.GameName, imported.GameID, imported.GameCode)
+	return nil
+}
+
+func generateKey(algorithm, dir string) error {
+	keyID, err := jwtkeys.Generate(dir, algorithm)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created key %s\n", keyID)
+	fmt.Printf("to sign with it, deploy with JWT_KEYS_DIR=%s and JWT_SIGNING_KEY_ID=%s\n", dir, keyID)
+	fmt.Println("keep the previous key file until every token it signed has expired")
+	return nil
+}
+
+func listKeys() error {
+	keys, err := jwtkeys.Get()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys.Keys() {
+		marker := ""
+		if key.ID == keys.SigningKeyID() {
+			marker = " (signing)"
+		}
+		fmt.Printf("%s\t%s%s\n", key.ID, key.Method.Alg(), marker)
+	}
+	return nil
+}
+
+// printConfig prints the config even when it is invalid, followed by what is wrong with it
+func printConfig() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		return err
+	}
+	if problems := cfg.Validate(); len(problems) > 0 {
+		return fmt.Errorf("invalid %s configuration:\n  - %s", cfg.Environment, strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
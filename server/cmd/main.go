@@ -1,16 +1,26 @@
 package main
 
 import (
+	"context"
 	"embed"
-	"errors"
+	"flag"
 	"fmt"
 	"io/fs"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
 	"github.com/jacobtie/rating-party/server/internal/handlers"
+	"github.com/jacobtie/rating-party/server/internal/jobs"
 	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/jwtkeys"
 	"github.com/jacobtie/rating-party/server/internal/platform/logger"
+	"github.com/jacobtie/rating-party/server/internal/platform/metrics"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
 
 	"github.com/rs/zerolog/log"
 )
@@ -25,8 +35,22 @@ var (
 var clientDir embed.FS
 
 func main() {
+	configFile := flag.String("config", "", "YAML or TOML config file, overridden by environment variables")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+	}
+	flag.Parse()
+	config.SetFile(*configFile)
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := run(); err != nil {
 		log.Err(err).Msg("failed to run server")
+		os.Exit(1)
 	}
 }
 
@@ -35,28 +59,118 @@ func run() error {
 	if err != nil {
 		return err
 	}
-	logger.InitLogger(cfg.Environment, cfg.Instance, BUILD_TAG, BUILD_GIT_HASH, BUILD_DATE)
+	logger.InitLogger(cfg.Environment, cfg.Log.Level, cfg.Instance, BUILD_TAG, BUILD_GIT_HASH, BUILD_DATE)
 	logger.Get().Info().Msg("starting rating party server")
-	db, err := db.New(cfg.DB.DBUser, cfg.DB.DBPass, cfg.DB.DBURI, cfg.DB.DBName)
+	// Fail at startup rather than on the first sign in when a key file is broken
+	keys, err := jwtkeys.Get()
+	if err != nil {
+		return err
+	}
+	logger.Get().Info().Str("signingKeyId", keys.SigningKeyID()).Msg("loaded signing keys")
+	shutdownTracing, err := tracing.Init(context.Background(), cfg, BUILD_TAG)
+	if err != nil {
+		return err
+	}
+	// Spans still buffered are flushed last so the shutdown itself is traced
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Get().Err(err).Msg("failed to flush traces")
+		}
+	}()
+	// The first SIGINT or SIGTERM shuts down gracefully, a second one exits straight away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	db, err := db.New(ctx, cfg)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Get().Err(err).Msg("failed to close database connections")
+		}
+	}()
+	if err := metrics.RegisterDB(db.DB.DB); err != nil {
+		return fmt.Errorf("failed to register database metrics: %w", err)
+	}
+	if err := metrics.RegisterActivity(game.NewController(cfg, db).GetActivity); err != nil {
+		return fmt.Errorf("failed to register activity metrics: %w", err)
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobsWG sync.WaitGroup
+	jobsWG.Add(2)
+	go func() {
+		defer jobsWG.Done()
+		jobs.RunPurge(jobsCtx, cfg, db)
+	}()
+	go func() {
+		defer jobsWG.Done()
+		jobs.RunRevocationSync(jobsCtx, cfg, db)
+	}()
+	// Jobs stop after the server has drained so requests still being handled are not affected
+	defer func() {
+		stopJobs()
+		jobsWG.Wait()
+	}()
 	strippedClientDir, err := fs.Sub(clientDir, "dist")
 	if err != nil {
 		return fmt.Errorf("failed to strip client directory prefix: %w", err)
 	}
+	build := handlers.BuildInfo{
+		Version:   BUILD_TAG,
+		Revision:  BUILD_GIT_HASH,
+		BuildDate: BUILD_DATE,
+	}
 	server := &http.Server{
 		Addr:         cfg.Web.APIHost,
-		Handler:      handlers.NewAPI(cfg, db, strippedClientDir),
+		Handler:      handlers.NewAPI(cfg, db, strippedClientDir, build),
 		ReadTimeout:  cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeout,
 	}
+	serverErrors := make(chan error, 2)
+	go func() {
+		serverErrors <- server.ListenAndServe()
+	}()
+	var metricsServer *http.Server
+	if cfg.Web.MetricsHost != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:        cfg.Web.MetricsHost,
+			Handler:     metricsMux,
+			ReadTimeout: cfg.Web.ReadTimeout,
+		}
+		go func() {
+			serverErrors <- metricsServer.ListenAndServe()
+		}()
+		logger.Get().Info().Str("host", cfg.Web.MetricsHost).Msg("serving metrics")
+	}
 	logger.Get().Info().
 		Str("environment", string(cfg.Environment)).
 		Str("host", cfg.Web.APIHost).
 		Msg("service started")
-	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	select {
+	case err := <-serverErrors:
 		return fmt.Errorf("failed while running server: %w", err)
+	case <-ctx.Done():
+	}
+	stop()
+	logger.Get().Info().Str("timeout", cfg.Web.ShutdownTimeout.String()).Msg("shutting down, draining requests")
+	// Shutdown stops accepting connections, closes idle ones and waits for in flight requests to finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Get().Err(err).Msg("requests did not finish in time, closing remaining connections")
+		if err := server.Close(); err != nil {
+			return fmt.Errorf("failed to close server: %w", err)
+		}
+	}
+	// Metrics are served until the very end so the drain itself can be watched
+	if metricsServer != nil {
+		if err := metricsServer.Close(); err != nil {
+			logger.Get().Err(err).Msg("failed to close metrics server")
+		}
 	}
 	logger.Get().Info().Msg("service stopped")
 	return nil
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jacobtie/rating-party/server/internal/platform/namegenerator"
@@ -16,15 +17,79 @@ type Config struct {
 		APIHost      string        `default:":3000" envconfig:"API_HOST"`
 		ReadTimeout  time.Duration `default:"10s" envconfig:"READ_TIMEOUT"`
 		WriteTimeout time.Duration `default:"15s" envconfig:"WRITE_TIMEOUT"`
+		PublicURL    string        `default:"http://localhost:3000" envconfig:"PUBLIC_URL"`
+		// TrustProxy takes the client address from X-Forwarded-For, only enable it behind a proxy that sets the header
+		TrustProxy bool `default:"false" envconfig:"TRUST_PROXY"`
+		// TrustedProxyHops is how many proxies in front of the server append to X-Forwarded-For
+		TrustedProxyHops int `default:"1" envconfig:"TRUSTED_PROXY_HOPS"`
+		// ShutdownTimeout is how long in flight requests get to finish after SIGTERM before they are cut off
+		ShutdownTimeout time.Duration `default:"20s" envconfig:"SHUTDOWN_TIMEOUT"`
+		// MetricsHost serves /metrics on its own listener so it can be kept off the public port.
+		// When it is empty /metrics is served with the API instead, and only to the admin.
+		MetricsHost string `default:":9090" envconfig:"METRICS_HOST"`
+	}
+	// Tracing sends spans to an OTLP collector over HTTP, such as http://localhost:4318, when an endpoint is set
+	Tracing struct {
+		Endpoint    string  `envconfig:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+		ServiceName string  `default:"rating-party" envconfig:"OTEL_SERVICE_NAME"`
+		SampleRatio float64 `default:"1" envconfig:"TRACE_SAMPLE_RATIO"`
+	}
+	Log struct {
+		// Level defaults to debug locally and info in production
+		Level string `envconfig:"LOG_LEVEL"`
 	}
 	DB struct {
 		DBUser string `default:"postgres" envconfig:"DB_USER"`
-		DBPass string `default:"postgres" envconfig:"DB_PASS"`
+		DBPass string `default:"postgres" envconfig:"DB_PASS" redact:"true"`
 		DBURI  string `default:"localhost:5432" envconfig:"DB_URI"`
 		DBName string `default:"ratingparty" envconfig:"DB_NAME"`
+		// SSLMode is passed to Postgres as sslmode, and Options are any other connection parameters as a query string
+		SSLMode string `default:"disable" envconfig:"DB_SSLMODE"`
+		Options string `envconfig:"DB_OPTIONS"`
+		// Connections are reused up to the lifetime and closed after sitting idle, so a restarted
+		// database or a failover is picked up without restarting the server
+		MaxOpenConns    int           `default:"20" envconfig:"DB_MAX_OPEN_CONNS"`
+		MaxIdleConns    int           `default:"5" envconfig:"DB_MAX_IDLE_CONNS"`
+		ConnMaxLifetime time.Duration `default:"30m" envconfig:"DB_CONN_MAX_LIFETIME"`
+		ConnMaxIdleTime time.Duration `default:"5m" envconfig:"DB_CONN_MAX_IDLE_TIME"`
+		// ConnectTimeout is how long startup keeps retrying while the database is unreachable,
+		// for example while it is still starting up next to the server
+		ConnectTimeout time.Duration `default:"1m" envconfig:"DB_CONNECT_TIMEOUT"`
+	}
+	AdminPasscode  string `default:"ivory" envconfig:"ADMIN_PASSCODE" redact:"true"`
+	AdminJWTSecret string `default:"ebony" envconfig:"ADMIN_JWT_SECRET" redact:"true"`
+	// Extra signing keys are read from files in the keys directory, named after their key ID. To rotate, add a
+	// new key file and make it the signing key. Keep the old file until every token it signed has expired.
+	JWT struct {
+		KeysDir      string `envconfig:"JWT_KEYS_DIR"`
+		SigningKeyID string `default:"default" envconfig:"JWT_SIGNING_KEY_ID"`
+	}
+	// Deleted games can be restored until the retention window has passed, after which they are purged
+	GameRetention time.Duration `default:"720h" envconfig:"GAME_RETENTION"`
+	PurgeInterval time.Duration `default:"1h" envconfig:"PURGE_INTERVAL"`
+	// RevocationSyncInterval is how quickly sessions revoked on another instance are rejected by this one
+	RevocationSyncInterval time.Duration `default:"30s" envconfig:"REVOCATION_SYNC_INTERVAL"`
+	// Access tokens are short lived and renewed with a refresh token. Each refresh rotates the refresh token
+	// and extends the session, so a session only ends after going unused for the refresh token lifetime.
+	AccessTokenTTL       time.Duration `default:"15m" envconfig:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL      time.Duration `default:"168h" envconfig:"REFRESH_TOKEN_TTL"`
+	AdminRefreshTokenTTL time.Duration `default:"12h" envconfig:"ADMIN_REFRESH_TOKEN_TTL"`
+	// InviteTTL is how long invite links stay valid when the host does not choose
+	InviteTTL time.Duration `default:"168h" envconfig:"INVITE_TTL"`
+	// Failed sign ins are limited per client address and per username. After the free attempts each
+	// further failure locks sign in out for twice as long as the last, up to the maximum lockout.
+	SignInLimit struct {
+		FreeAttempts int           `default:"5" envconfig:"SIGNIN_FREE_ATTEMPTS"`
+		BaseLockout  time.Duration `default:"2s" envconfig:"SIGNIN_BASE_LOCKOUT"`
+		MaxLockout   time.Duration `default:"15m" envconfig:"SIGNIN_MAX_LOCKOUT"`
+		// MaxUsernameDelay caps how far apart attempts for a username that keeps failing from many addresses are spaced.
+		// Attempts are held for up to this long, so it must be well under WRITE_TIMEOUT.
+		MaxUsernameDelay time.Duration `default:"5s" envconfig:"SIGNIN_MAX_USERNAME_DELAY"`
+		// ResetAfter forgets a client's failures once it has gone this long without failing
+		ResetAfter time.Duration `default:"1h" envconfig:"SIGNIN_RESET_AFTER"`
 	}
-	AdminPasscode  string `default:"ivory" envconfig:"ADMIN_PASSCODE"`
-	AdminJWTSecret string `default:"ebony" envconfig:"ADMIN_JWT_SECRET"`
+	// sources records where each setting came from, keyed by its environment variable
+	sources map[string]string
 }
 
 type ENV string
@@ -40,16 +105,43 @@ var (
 
 func Get() (*Config, error) {
 	if cfg == nil {
-		var config Config
-		if err := envconfig.Process("", &config); err != nil {
-			return nil, fmt.Errorf("[db.Get] failed to process env vars: %w", err)
+		config, err := Load()
+		if err != nil {
+			return nil, err
 		}
-		cfg = &config
-		cfg.Instance = namegenerator.Generator()
+		if problems := config.Validate(); len(problems) > 0 {
+			return nil, fmt.Errorf("[config.Get] invalid %s configuration:\n  - %s", config.Environment, strings.Join(problems, "\n  - "))
+		}
+		cfg = config
 	}
 	return cfg, nil
 }
 
+// Load reads the config from its defaults, the config file and the environment without validating it
+func Load() (*Config, error) {
+	var config Config
+	if err := envconfig.Process("", &config); err != nil {
+		return nil, fmt.Errorf("[config.Load] failed to process env vars: %w", err)
+	}
+	config.sources = make(map[string]string)
+	for _, s := range config.settings() {
+		config.sources[s.envAlt] = sourceDefault
+		if s.fromEnv() {
+			config.sources[s.envAlt] = sourceEnv
+		}
+	}
+	if path := configFile(); path != "" {
+		if err := config.applyFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if problems := config.loadSecretFiles(); len(problems) > 0 {
+		return nil, fmt.Errorf("[config.Load] invalid secret files:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	config.Instance = namegenerator.Generator()
+	return &config, nil
+}
+
 func MustGet() *Config {
 	cfg, err := Get()
 	if err != nil {
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are layered, each layer overriding the one before it:
+//
+//	default  the default tag on the field
+//	file     the config file, YAML or TOML depending on its extension
+//	env      the environment variable, or the file named by <NAME>_FILE for secrets
+const (
+	sourceDefault    = "default"
+	sourceFile       = "file"
+	sourceEnv        = "env"
+	sourceSecretFile = "env file"
+)
+
+var file string
+
+// SetFile sets the config file to load, it must be called before the config is first loaded.
+// Without it the file is taken from CONFIG_FILE, and no file is loaded when that is not set either.
+func SetFile(path string) {
+	file = path
+}
+
+func configFile() string {
+	if file != "" {
+		return file
+	}
+	return os.Getenv("CONFIG_FILE")
+}
+
+// setting is a single value in the config, found by walking the struct the same way envconfig does
+type setting struct {
+	path   []string
+	envKey string
+	envAlt string
+	field  reflect.Value
+	redact bool
+}
+
+func (c *Config) settings() []*setting {
+	return walkSettings(reflect.ValueOf(c).Elem(), nil, "")
+}
+
+func walkSettings(v reflect.Value, path []string, prefix string) []*setting {
+	settings := make([]*setting, 0)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("envconfig")
+		key := tag
+		if key == "" {
+			key = field.Name
+		}
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		key = strings.ToUpper(key)
+		fieldPath := append(append([]string{}, path...), fileKey(field.Name))
+		if field.Type.Kind() == reflect.Struct {
+			settings = append(settings, walkSettings(v.Field(i), fieldPath, key)...)
+			continue
+		}
+		// Fields without a tag, like the instance name, are not settings
+		if tag == "" {
+			continue
+		}
+		settings = append(settings, &setting{
+			path:   fieldPath,
+			envKey: key,
+			envAlt: strings.ToUpper(tag),
+			field:  v.Field(i),
+			redact: field.Tag.Get("redact") == "true",
+		})
+	}
+	return settings
+}
+
+// fileKey is the field name in lower camel case, so APIHost is apiHost
+func fileKey(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		// The last capital of an acronym starts the next word
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// matchKey makes file keys case insensitive and lets words be separated, so apiHost, APIHost and api_host match
+func matchKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+}
+
+func (s *setting) name() string {
+	return strings.Join(s.path, ".")
+}
+
+func (s *setting) fromEnv() bool {
+	if _, ok := os.LookupEnv(s.envKey); ok {
+		return true
+	}
+	_, ok := os.LookupEnv(s.envAlt)
+	return ok
+}
+
+func (s *setting) set(value string) error {
+	switch {
+	case s.field.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.field.SetInt(int64(d))
+	case s.field.Kind() == reflect.String:
+		s.field.SetString(value)
+	case s.field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		s.field.SetBool(b)
+	case s.field.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		s.field.SetFloat(f)
+	case s.field.Kind() == reflect.Int:
+		i, err := strconv.ParseInt(value, 0, s.field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		s.field.SetInt(i)
+	default:
+		return fmt.Errorf("unsupported type %s", s.field.Type())
+	}
+	return nil
+}
+
+// applyFile sets every setting found in the config file, except those also set in the environment
+func (c *Config) applyFile(path string) error {
+	values, err := readFile(path)
+	if err != nil {
+		return fmt.Errorf("[config.applyFile] failed to read config file %s: %w", path, err)
+	}
+	settings := make(map[string]*setting)
+	for _, s := range c.settings() {
+		settings[matchKey(s.name())] = s
+	}
+	problems := make([]string, 0)
+	var apply func(values map[string]any, path []string)
+	apply = func(values map[string]any, path []string) {
+		for key, value := range values {
+			keyPath := append(append([]string{}, path...), key)
+			name := strings.Join(keyPath, ".")
+			if nested, ok := value.(map[string]any); ok {
+				apply(nested, keyPath)
+				continue
+			}
+			s, ok := settings[matchKey(name)]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s is not a setting", name))
+				continue
+			}
+			if value == nil || s.fromEnv() {
+				continue
+			}
+			if _, ok := value.([]any); ok {
+				problems = append(problems, fmt.Sprintf("%s must be a single value", name))
+				continue
+			}
+			if err := s.set(fmt.Sprint(value)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s is invalid: %v", name, err))
+				continue
+			}
+			c.sources[s.envAlt] = sourceFile
+		}
+	}
+	apply(values, nil)
+	if len(problems) > 0 {
+		return fmt.Errorf("[config.applyFile] invalid config file %s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unknown config file type, use .yaml, .yml or .toml")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[redacted]"
+
+// Print writes the effective config in the config file format, noting where each setting came from.
+// Secrets are redacted so the output can be shared.
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		parent := root
+		for _, key := range s.path[:len(s.path)-1] {
+			parent = childMapping(parent, key)
+		}
+		value := printValue(s)
+		value.LineComment = c.sources[s.envAlt] + " " + s.envAlt
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: s.path[len(s.path)-1]}, value)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("[config.Print] failed to write config: %w", err)
+	}
+	return encoder.Close()
+}
+
+func childMapping(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	return child
+}
+
+func printValue(s *setting) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(s.field.Interface())}
+	switch {
+	case s.redact && node.Value != "":
+		node.Value = redacted
+	case s.field.Type() == reflect.TypeOf(time.Duration(0)):
+		node.Value = time.Duration(s.field.Int()).String()
+	case s.field.Kind() == reflect.Bool:
+		node.Tag = "!!bool"
+	case s.field.Kind() == reflect.Int:
+		node.Tag = "!!int"
+	case s.field.Kind() == reflect.Float64:
+		// A whole number needs a decimal point to read back as a float rather than an int
+		node.Tag = "!!float"
+		node.Value = strconv.FormatFloat(s.field.Float(), 'f', -1, 64)
+		if !strings.ContainsAny(node.Value, ".eE") {
+			node.Value += ".0"
+		}
+	}
+	return node
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// The shipped defaults are only meant for local development
+const (
+	defaultAdminPasscode  = "ivory"
+	defaultAdminJWTSecret = "ebony"
+	defaultDBPass         = "postgres"
+	minPasscodeLength     = 8
+	minJWTSecretLength    = 32
+)
+
+var validSSLModes = map[string]bool{
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// secretFiles lists the settings that can be read from a file, for example a mounted container secret,
+// by setting <NAME>_FILE to the path of the file instead of setting <NAME>
+func (c *Config) secretFiles() map[string]*string {
+	return map[string]*string{
+		"ADMIN_PASSCODE":   &c.AdminPasscode,
+		"ADMIN_JWT_SECRET": &c.AdminJWTSecret,
+		"DB_USER":          &c.DB.DBUser,
+		"DB_PASS":          &c.DB.DBPass,
+	}
+}
+
+// loadSecretFiles replaces settings with the contents of their files and returns any problems
+func (c *Config) loadSecretFiles() []string {
+	problems := make([]string, 0)
+	for name, value := range c.secretFiles() {
+		path, ok := os.LookupEnv(name + "_FILE")
+		if !ok {
+			continue
+		}
+		if _, ok := os.LookupEnv(name); ok {
+			problems = append(problems, fmt.Sprintf("%s and %s_FILE are both set, set only one of them", name, name))
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s_FILE could not be read: %v", name, err))
+			continue
+		}
+		// Files written by editors and echo usually end with a newline that is not part of the secret
+		secret := strings.TrimRight(string(data), "\r\n")
+		if secret == "" {
+			problems = append(problems, fmt.Sprintf("%s_FILE points to an empty file %s", name, path))
+			continue
+		}
+		*value = secret
+		c.sources[name] = sourceSecretFile
+	}
+	return problems
+}
+
+// Validate lists every problem with the config so they can all be fixed in one go. Every environment checks
+// that the settings make sense, production also refuses the default and weak secrets.
+func (c *Config) Validate() []string {
+	problems := make([]string, 0)
+	if c.Environment != ENV_LOCAL && c.Environment != ENV_PROD {
+		problems = append(problems, fmt.Sprintf("APP_ENV is %q, it must be %q or %q", c.Environment, ENV_LOCAL, ENV_PROD))
+	}
+	publicURL, err := url.Parse(c.Web.PublicURL)
+	if err != nil || publicURL.Scheme == "" || publicURL.Host == "" {
+		problems = append(problems, fmt.Sprintf("PUBLIC_URL %q is not an absolute URL", c.Web.PublicURL))
+	}
+	if c.Web.TrustedProxyHops < 1 {
+		problems = append(problems, "TRUSTED_PROXY_HOPS must be at least 1")
+	}
+	if c.Web.ShutdownTimeout <= 0 {
+		problems = append(problems, "SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.AccessTokenTTL <= 0 {
+		problems = append(problems, "ACCESS_TOKEN_TTL must be positive")
+	}
+	if c.RefreshTokenTTL < c.AccessTokenTTL || c.AdminRefreshTokenTTL < c.AccessTokenTTL {
+		problems = append(problems, "REFRESH_TOKEN_TTL and ADMIN_REFRESH_TOKEN_TTL must be at least ACCESS_TOKEN_TTL")
+	}
+	if c.InviteTTL <= 0 {
+		problems = append(problems, "INVITE_TTL must be positive")
+	}
+	if c.GameRetention < 0 {
+		problems = append(problems, "GAME_RETENTION cannot be negative")
+	}
+	if c.PurgeInterval <= 0 || c.RevocationSyncInterval <= 0 {
+		problems = append(problems, "PURGE_INTERVAL and REVOCATION_SYNC_INTERVAL must be positive")
+	}
+	if c.SignInLimit.FreeAttempts < 1 {
+		problems = append(problems, "SIGNIN_FREE_ATTEMPTS must be at least 1")
+	}
+	if c.SignInLimit.BaseLockout <= 0 || c.SignInLimit.MaxLockout < c.SignInLimit.BaseLockout {
+		problems = append(problems, "SIGNIN_BASE_LOCKOUT must be positive and no more than SIGNIN_MAX_LOCKOUT")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "TRACE_SAMPLE_RATIO must be between 0 and 1")
+	}
+	if !validSSLModes[c.DB.SSLMode] {
+		problems = append(problems, fmt.Sprintf("DB_SSLMODE %q is not a Postgres sslmode, use disable, allow, prefer, require, verify-ca or verify-full", c.DB.SSLMode))
+	}
+	if _, err := url.ParseQuery(c.DB.Options); err != nil {
+		problems = append(problems, fmt.Sprintf("DB_OPTIONS must be a query string such as application_name=ratingparty&connect_timeout=5: %v", err))
+	}
+	if c.DB.MaxOpenConns < 1 || c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		problems = append(problems, "DB_MAX_OPEN_CONNS must be at least 1 and DB_MAX_IDLE_CONNS between 0 and DB_MAX_OPEN_CONNS")
+	}
+	if c.DB.ConnMaxLifetime < 0 || c.DB.ConnMaxIdleTime < 0 || c.DB.ConnectTimeout < 0 {
+		problems = append(problems, "DB_CONN_MAX_LIFETIME, DB_CONN_MAX_IDLE_TIME and DB_CONNECT_TIMEOUT cannot be negative")
+	}
+	if _, err := zerolog.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL %q is not a level, use trace, debug, info, warn or error", c.Log.Level))
+	}
+	if c.SignInLimit.MaxUsernameDelay <= 0 || c.SignInLimit.MaxUsernameDelay >= c.Web.WriteTimeout {
+		problems = append(problems, "SIGNIN_MAX_USERNAME_DELAY must be positive and less than WRITE_TIMEOUT")
+	}
+	if c.SignInLimit.ResetAfter <= 0 {
+		problems = append(problems, "SIGNIN_RESET_AFTER must be positive")
+	}
+	if c.signsWithAdminJWTSecret() && c.AdminJWTSecret == "" {
+		problems = append(problems, "ADMIN_JWT_SECRET is empty, set it or set JWT_SIGNING_KEY_ID to a key in JWT_KEYS_DIR")
+	}
+	if c.Environment != ENV_PROD {
+		return problems
+	}
+
+	if c.AdminPasscode == defaultAdminPasscode || len(c.AdminPasscode) < minPasscodeLength {
+		problems = append(problems, fmt.Sprintf("ADMIN_PASSCODE is the default or too short, set a passcode of at least %d characters", minPasscodeLength))
+	}
+	// Once a key file signs tokens the secret can be left empty, but while it is set it still verifies tokens
+	if c.AdminJWTSecret != "" && (c.AdminJWTSecret == defaultAdminJWTSecret || len(c.AdminJWTSecret) < minJWTSecretLength) {
+		problems = append(problems, fmt.Sprintf("ADMIN_JWT_SECRET is the default or too short, set a random secret of at least %d characters such as the output of `openssl rand -base64 48`", minJWTSecretLength))
+	}
+	if c.DB.DBPass == defaultDBPass || c.DB.DBPass == "" {
+		problems = append(problems, "DB_PASS is empty or the default, set the database password")
+	}
+	// A URL that is not absolute has already been reported above
+	if publicURL != nil && publicURL.Host != "" && (publicURL.Scheme != "https" || publicURL.Hostname() == "localhost") {
+		problems = append(problems, fmt.Sprintf("PUBLIC_URL %q must be the public https address guests use, it is printed on scorecards and invites", c.Web.PublicURL))
+	}
+	return problems
+}
+
+// signsWithAdminJWTSecret reports whether tokens are signed with the HMAC key made from ADMIN_JWT_SECRET
+// rather than a key file
+func (c *Config) signsWithAdminJWTSecret() bool {
+	return c.JWT.SigningKeyID == "" || c.JWT.SigningKeyID == "default"
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// unsetenv removes the environment variable for the rest of the test
+func unsetenv(t *testing.T, name string) {
+	t.Helper()
+	t.Setenv(name, "")
+	os.Unsetenv(name)
+}
+
+// newProdConfig loads the defaults and replaces every secret so the config passes the production checks
+func newProdConfig(t *testing.T) *Config {
+	t.Helper()
+	for _, name := range []string{"CONFIG_FILE", "APP_ENV", "PUBLIC_URL", "ADMIN_PASSCODE", "ADMIN_JWT_SECRET", "DB_PASS", "JWT_SIGNING_KEY_ID"} {
+		unsetenv(t, name)
+		unsetenv(t, name+"_FILE")
+	}
+	c, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	c.Environment = ENV_PROD
+	c.Web.PublicURL = "https://ratings.example.com"
+	c.AdminPasscode = "correct horse"
+	c.AdminJWTSecret = strings.Repeat("k", minJWTSecretLength)
+	c.DB.DBPass = "battery staple"
+	return c
+}
+
+func TestValidateProductionSecrets(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		// problem is part of the only expected problem, or empty when the config is valid
+		problem string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "default passcode", modify: func(c *Config) { c.AdminPasscode = defaultAdminPasscode }, problem: "ADMIN_PASSCODE"},
+		{name: "short passcode", modify: func(c *Config) { c.AdminPasscode = strings.Repeat("p", minPasscodeLength-1) }, problem: "ADMIN_PASSCODE"},
+		{name: "shortest passcode", modify: func(c *Config) { c.AdminPasscode = strings.Repeat("p", minPasscodeLength) }},
+		{name: "default JWT secret", modify: func(c *Config) { c.AdminJWTSecret = defaultAdminJWTSecret }, problem: "ADMIN_JWT_SECRET is the default"},
+		{name: "short JWT secret", modify: func(c *Config) { c.AdminJWTSecret = strings.Repeat("k", minJWTSecretLength-1) }, problem: "ADMIN_JWT_SECRET is the default"},
+		{name: "empty JWT secret while it signs tokens", modify: func(c *Config) { c.AdminJWTSecret = "" }, problem: "ADMIN_JWT_SECRET is empty"},
+		{name: "empty JWT secret with a key file", modify: func(c *Config) { c.AdminJWTSecret = ""; c.JWT.SigningKeyID = "2024" }},
+		{name: "default JWT secret with a key file", modify: func(c *Config) { c.AdminJWTSecret = defaultAdminJWTSecret; c.JWT.SigningKeyID = "2024" }, problem: "ADMIN_JWT_SECRET is the default"},
+		{name: "default database password", modify: func(c *Config) { c.DB.DBPass = defaultDBPass }, problem: "DB_PASS"},
+		{name: "empty database password", modify: func(c *Config) { c.DB.DBPass = "" }, problem: "DB_PASS"},
+		{name: "plain http public URL", modify: func(c *Config) { c.Web.PublicURL = "http://ratings.example.com" }, problem: "PUBLIC_URL"},
+		{name: "localhost public URL", modify: func(c *Config) { c.Web.PublicURL = "https://localhost:3000" }, problem: "PUBLIC_URL"},
+		{name: "relative public URL", modify: func(c *Config) { c.Web.PublicURL = "/ratings" }, problem: "is not an absolute URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newProdConfig(t)
+			tt.modify(c)
+			problems := c.Validate()
+			if tt.problem == "" {
+				if len(problems) > 0 {
+					t.Fatalf("valid config had problems: %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0], tt.problem) {
+				t.Fatalf("problems were %v, want one about %s", problems, tt.problem)
+			}
+		})
+	}
+}
+
+func TestValidateAllowsLocalDefaults(t *testing.T) {
+	c := newProdConfig(t)
+	c.Environment = ENV_LOCAL
+	c.Web.PublicURL = "http://localhost:3000"
+	c.AdminPasscode = defaultAdminPasscode
+	c.AdminJWTSecret = defaultAdminJWTSecret
+	c.DB.DBPass = defaultDBPass
+	if problems := c.Validate(); len(problems) > 0 {
+		t.Fatalf("local defaults had problems: %v", problems)
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		// file is written and ADMIN_PASSCODE_FILE points at it unless it is nil
+		file *string
+		// env sets ADMIN_PASSCODE alongside the file
+		env     bool
+		missing bool
+		want    string
+		problem string
+	}{
+		{name: "no file", want: "from the environment"},
+		{name: "trailing newline", file: ptr("hunter22\n"), want: "hunter22"},
+		{name: "windows line ending", file: ptr("hunter22\r\n"), want: "hunter22"},
+		{name: "several trailing newlines", file: ptr("hunter22\n\n"), want: "hunter22"},
+		{name: "spaces are kept", file: ptr(" hunter 22 \n"), want: " hunter 22 "},
+		{name: "no trailing newline", file: ptr("hunter22"), want: "hunter22"},
+		{name: "empty file", file: ptr(""), want: "from the environment", problem: "empty file"},
+		{name: "only a newline", file: ptr("\n"), want: "from the environment", problem: "empty file"},
+		{name: "both set", file: ptr("hunter22\n"), env: true, want: "from the environment", problem: "ADMIN_PASSCODE and ADMIN_PASSCODE_FILE are both set"},
+		{name: "missing file", missing: true, want: "from the environment", problem: "ADMIN_PASSCODE_FILE could not be read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name := range (&Config{}).secretFiles() {
+				unsetenv(t, name)
+				unsetenv(t, name+"_FILE")
+			}
+			path := filepath.Join(t.TempDir(), "passcode")
+			if tt.file != nil {
+				if err := os.WriteFile(path, []byte(*tt.file), 0o600); err != nil {
+					t.Fatalf("failed to write secret file: %v", err)
+				}
+			}
+			if tt.file != nil || tt.missing {
+				t.Setenv("ADMIN_PASSCODE_FILE", path)
+			}
+			if tt.env {
+				t.Setenv("ADMIN_PASSCODE", "from the environment")
+			}
+			c := &Config{AdminPasscode: "from the environment", sources: map[string]string{"ADMIN_PASSCODE": sourceEnv}}
+			problems := c.loadSecretFiles()
+			if c.AdminPasscode != tt.want {
+				t.Fatalf("passcode was %q, want %q", c.AdminPasscode, tt.want)
+			}
+			if tt.problem == "" {
+				if len(problems) > 0 {
+					t.Fatalf("secret file had problems: %v", problems)
+				}
+				if tt.file != nil && c.sources["ADMIN_PASSCODE"] != sourceSecretFile {
+					t.Fatalf("passcode source was %q, want %q", c.sources["ADMIN_PASSCODE"], sourceSecretFile)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0], tt.problem) {
+				t.Fatalf("problems were %v, want one about %s", problems, tt.problem)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
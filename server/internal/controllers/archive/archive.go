@@ -0,0 +1,144 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/controllers/rating"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+)
+
+type Controller struct {
+	cfg            *config.Config
+	db             *db.DB
+	gameController *game.Controller
+}
+
+func NewController(cfg *config.Config, db *db.DB, gameController *game.Controller) *Controller {
+	return &Controller{
+		cfg:            cfg,
+		db:             db,
+		gameController: gameController,
+	}
+}
+
+// validate checks the archive can be read by this server and that every reference inside it resolves
+func validate(a *Archive) error {
+	if a.Format != Format {
+		return fmt.Errorf("[archive.validate] not a game archive: %w", werrors.ErrBadRequest)
+	}
+	if a.Version < 1 || a.Version > Version {
+		return fmt.Errorf("[archive.validate] unsupported archive version %d, this server reads versions 1 to %d: %w", a.Version, Version, werrors.ErrBadRequest)
+	}
+	if a.Game == nil || strings.TrimSpace(a.Game.GameName) == "" {
+		return fmt.Errorf("[archive.validate] archive has no game: %w", werrors.ErrBadRequest)
+	}
+	if !rating.IsValidStrategy(a.Game.RankingStrategy) {
+		return fmt.Errorf("[archive.validate] unknown ranking strategy %q: %w", a.Game.RankingStrategy, werrors.ErrBadRequest)
+	}
+	if a.Game.MaxJoins < 0 || a.Game.JoinCount < 0 {
+		return fmt.Errorf("[archive.validate] max joins and join count cannot be negative: %w", werrors.ErrBadRequest)
+	}
+	if a.Game.TieBreakers != "" {
+		for _, tieBreaker := range strings.Split(a.Game.TieBreakers, ",") {
+			if !rating.IsValidTieBreaker(tieBreaker) {
+				return fmt.Errorf("[archive.validate] unknown tie breaker %q: %w", tieBreaker, werrors.ErrBadRequest)
+			}
+		}
+	}
+	participants := make(map[string]bool, len(a.Participants))
+	usernames := make(map[string]bool, len(a.Participants))
+	for _, participant := range a.Participants {
+		if participant == nil || participant.ParticipantID == "" || participants[participant.ParticipantID] {
+			return fmt.Errorf("[archive.validate] participant is missing or has a duplicate ID: %w", werrors.ErrBadRequest)
+		}
+		if participant.Username == "" || usernames[participant.Username] {
+			return fmt.Errorf("[archive.validate] participant %s is missing or has a duplicate username: %w", participant.ParticipantID, werrors.ErrBadRequest)
+		}
+		participants[participant.ParticipantID] = true
+		usernames[participant.Username] = true
+	}
+	wines := make(map[string]*Wine, len(a.Wines))
+	for _, wine := range a.Wines {
+		if wine == nil || wine.WineID == "" || wines[wine.WineID] != nil {
+			return fmt.Errorf("[archive.validate] wine is missing or has a duplicate ID: %w", werrors.ErrBadRequest)
+		}
+		wines[wine.WineID] = wine
+	}
+	for _, wine := range a.Wines {
+		if wine.DuplicateOf == "" {
+			continue
+		}
+		original, ok := wines[wine.DuplicateOf]
+		if !ok || original.DuplicateOf != "" || original.WineID == wine.WineID {
+			return fmt.Errorf("[archive.validate] wine %s is a duplicate of an unknown or duplicate wine: %w", wine.WineID, werrors.ErrBadRequest)
+		}
+	}
+	rated := make(map[string]bool, len(a.Ratings))
+	for _, r := range a.Ratings {
+		if r == nil || !participants[r.ParticipantID] || wines[r.WineID] == nil {
+			return fmt.Errorf("[archive.validate] rating references an unknown participant or wine: %w", werrors.ErrBadRequest)
+		}
+		// Scores are held to the same scorecard as ratings made in the app
+		if err := rating.ValidateScores(&rating.Rating{
+			SightRating:   r.SightRating,
+			AromaRating:   r.AromaRating,
+			TasteRating:   r.TasteRating,
+			OverallRating: r.OverallRating,
+		}); err != nil {
+			return fmt.Errorf("[archive.validate] participant %s rates wine %s outside the scorecard: %w", r.ParticipantID, r.WineID, err)
+		}
+		key := r.ParticipantID + "/" + r.WineID
+		if rated[key] {
+			return fmt.Errorf("[archive.validate] participant %s rates wine %s more than once: %w", r.ParticipantID, r.WineID, werrors.ErrBadRequest)
+		}
+		rated[key] = true
+	}
+	return nil
+}
+
+// remap returns a copy of a validated archive with fresh UUIDs, with every reference pointing at the new IDs
+func remap(a *Archive) *Archive {
+	g := *a.Game
+	g.GameID = uuid.New().String()
+	remapped := &Archive{
+		Format:       a.Format,
+		Version:      a.Version,
+		ExportedAt:   a.ExportedAt,
+		Game:         &g,
+		Participants: make([]*Participant, len(a.Participants)),
+		Wines:        make([]*Wine, len(a.Wines)),
+		Ratings:      make([]*Rating, len(a.Ratings)),
+	}
+	participantIDs := make(map[string]string, len(a.Participants))
+	for i, p := range a.Participants {
+		participant := *p
+		participant.ParticipantID = uuid.New().String()
+		participantIDs[p.ParticipantID] = participant.ParticipantID
+		remapped.Participants[i] = &participant
+	}
+	wineIDs := make(map[string]string, len(a.Wines))
+	for _, w := range a.Wines {
+		wineIDs[w.WineID] = uuid.New().String()
+	}
+	for i, w := range a.Wines {
+		wine := *w
+		wine.WineID = wineIDs[w.WineID]
+		if w.DuplicateOf != "" {
+			wine.DuplicateOf = wineIDs[w.DuplicateOf]
+		}
+		remapped.Wines[i] = &wine
+	}
+	for i, r := range a.Ratings {
+		rated := *r
+		rated.RatingID = uuid.New().String()
+		rated.ParticipantID = participantIDs[r.ParticipantID]
+		rated.WineID = wineIDs[r.WineID]
+		remapped.Ratings[i] = &rated
+	}
+	return remapped
+}
@@ -0,0 +1,165 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+)
+
+func newTestArchive() *Archive {
+	createdAt := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	return &Archive{
+		Format:     Format,
+		Version:    Version,
+		ExportedAt: createdAt.Add(6 * time.Hour),
+		Game: &Game{
+			GameID:                 "game",
+			GameName:               "Spring tasting",
+			GameCode:               "ABC123",
+			IsRunning:              true,
+			AreResultsShared:       true,
+			RankingStrategy:        "median",
+			TieBreakers:            "ratingCount,shared",
+			MergeDuplicates:        true,
+			CodeDisabled:           true,
+			DisableCodeWhenStopped: true,
+			CodeExpiresAt:          &expiresAt,
+			MaxJoins:               12,
+			JoinCount:              2,
+			CreatedAt:              createdAt,
+			UpdatedAt:              createdAt,
+		},
+		Participants: []*Participant{
+			{ParticipantID: "alice", Username: "alice", CreatedAt: createdAt, UpdatedAt: createdAt},
+			{ParticipantID: "bob", Username: "bob", CreatedAt: createdAt, UpdatedAt: createdAt},
+		},
+		Wines: []*Wine{
+			{WineID: "red", WineName: "Red", WineCode: "A", WineYear: 2019, CreatedAt: createdAt, UpdatedAt: createdAt},
+			{WineID: "white", WineName: "White", WineCode: "B", WineYear: 2021, CreatedAt: createdAt, UpdatedAt: createdAt},
+			{WineID: "red-again", WineName: "Red", WineCode: "C", WineYear: 2019, DuplicateOf: "red", CreatedAt: createdAt, UpdatedAt: createdAt},
+		},
+		Ratings: []*Rating{
+			{RatingID: "1", ParticipantID: "alice", WineID: "red", SightRating: 4, AromaRating: 5, TasteRating: 6, OverallRating: 3.5, Comments: "Lovely", CreatedAt: createdAt, UpdatedAt: createdAt},
+			{RatingID: "2", ParticipantID: "alice", WineID: "red-again", SightRating: 3, AromaRating: 5, TasteRating: 5, OverallRating: 3, CreatedAt: createdAt, UpdatedAt: createdAt},
+			{RatingID: "3", ParticipantID: "bob", WineID: "white", SightRating: 2, AromaRating: 3, TasteRating: 4, OverallRating: 2, HostEntered: true, CreatedAt: createdAt, UpdatedAt: createdAt},
+		},
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	exported := newTestArchive()
+	body, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatalf("failed to encode archive: %v", err)
+	}
+	var imported Archive
+	if err := json.Unmarshal(body, &imported); err != nil {
+		t.Fatalf("failed to decode archive: %v", err)
+	}
+	if !reflect.DeepEqual(exported, &imported) {
+		t.Fatalf("archive changed on the way through JSON:\n%+v\n%+v", exported.Game, imported.Game)
+	}
+	if err := validate(&imported); err != nil {
+		t.Fatalf("exported archive did not validate: %v", err)
+	}
+
+	remapped := remap(&imported)
+	if err := validate(remapped); err != nil {
+		t.Fatalf("remapped archive did not validate: %v", err)
+	}
+	if remapped.Game.GameID == exported.Game.GameID {
+		t.Fatalf("game kept its archived ID")
+	}
+	// Everything but the ID survives the import, including the join settings
+	g := *remapped.Game
+	g.GameID = exported.Game.GameID
+	if !reflect.DeepEqual(&g, exported.Game) {
+		t.Fatalf("game settings changed on import:\n%+v\n%+v", exported.Game, &g)
+	}
+	participants := make(map[string]string)
+	for i, p := range remapped.Participants {
+		if p.ParticipantID == exported.Participants[i].ParticipantID || p.Username != exported.Participants[i].Username {
+			t.Fatalf("participant %s was not remapped", p.Username)
+		}
+		participants[p.ParticipantID] = p.Username
+	}
+	wines := make(map[string]string)
+	for i, w := range remapped.Wines {
+		if w.WineID == exported.Wines[i].WineID || w.WineCode != exported.Wines[i].WineCode {
+			t.Fatalf("wine %s was not remapped", w.WineCode)
+		}
+		wines[w.WineID] = w.WineCode
+	}
+	if original := wines[remapped.Wines[2].DuplicateOf]; original != "A" {
+		t.Fatalf("duplicate pointed at wine %q, want A", original)
+	}
+	for i, r := range remapped.Ratings {
+		want := exported.Ratings[i]
+		if r.RatingID == want.RatingID {
+			t.Fatalf("rating %s kept its archived ID", r.RatingID)
+		}
+		if participants[r.ParticipantID] != want.ParticipantID || wines[r.WineID] == "" {
+			t.Fatalf("rating %d points at the wrong participant or wine", i+1)
+		}
+		if r.SightRating != want.SightRating || r.OverallRating != want.OverallRating || r.Comments != want.Comments || r.HostEntered != want.HostEntered {
+			t.Fatalf("rating %d changed on import", i+1)
+		}
+	}
+}
+
+func TestValidateVersion1Archive(t *testing.T) {
+	body := []byte(`{
+		"format": "rating-party-game",
+		"version": 1,
+		"game": {"gameId": "game", "gameName": "Old game", "rankingStrategy": "mean", "tieBreakers": ""},
+		"participants": [],
+		"wines": [],
+		"ratings": []
+	}`)
+	var a Archive
+	if err := json.Unmarshal(body, &a); err != nil {
+		t.Fatalf("failed to decode archive: %v", err)
+	}
+	if err := validate(&a); err != nil {
+		t.Fatalf("version 1 archive did not validate: %v", err)
+	}
+	if a.Game.CodeDisabled || a.Game.CodeExpiresAt != nil || a.Game.MaxJoins != 0 {
+		t.Fatalf("version 1 archive imported with join settings: %+v", a.Game)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(a *Archive)
+	}{
+		{name: "unknown format", modify: func(a *Archive) { a.Format = "something-else" }},
+		{name: "newer version", modify: func(a *Archive) { a.Version = Version + 1 }},
+		{name: "no game", modify: func(a *Archive) { a.Game = nil }},
+		{name: "unknown ranking strategy", modify: func(a *Archive) { a.Game.RankingStrategy = "loudest" }},
+		{name: "unknown tie breaker", modify: func(a *Archive) { a.Game.TieBreakers = "ratingCount,coinFlip" }},
+		{name: "negative max joins", modify: func(a *Archive) { a.Game.MaxJoins = -1 }},
+		{name: "duplicate username", modify: func(a *Archive) { a.Participants[1].Username = "alice" }},
+		{name: "duplicate of a duplicate", modify: func(a *Archive) { a.Wines[1].DuplicateOf = "red-again" }},
+		{name: "rating for an unknown wine", modify: func(a *Archive) { a.Ratings[0].WineID = "rose" }},
+		{name: "wine rated twice", modify: func(a *Archive) { a.Ratings[1].WineID = "red" }},
+		{name: "score above the scorecard", modify: func(a *Archive) { a.Ratings[0].SightRating = 5 }},
+		{name: "negative score", modify: func(a *Archive) { a.Ratings[1].TasteRating = -1 }},
+		{name: "score that is not a number", modify: func(a *Archive) { a.Ratings[2].AromaRating = math.NaN() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestArchive()
+			tt.modify(a)
+			if err := validate(a); !errors.Is(err, werrors.ErrBadRequest) {
+				t.Fatalf("validate returned %v, want a bad request", err)
+			}
+		})
+	}
+}
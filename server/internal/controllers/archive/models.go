@@ -0,0 +1,73 @@
+package archive
+
+import "time"
+
+// Format identifies a JSON document as a game archive
+const Format = "rating-party-game"
+
+// Version is bumped whenever the archive layout changes in a way older servers cannot read.
+// Version 2 added the join settings of the game.
+const Version = 2
+
+// Archive is a complete, portable copy of a game. IDs are only used to link records inside the archive
+// and are replaced with fresh UUIDs when the archive is imported.
+type Archive struct {
+	Format       string         `json:"format"`
+	Version      int            `json:"version"`
+	ExportedAt   time.Time      `json:"exportedAt"`
+	Game         *Game          `json:"game"`
+	Participants []*Participant `json:"participants"`
+	Wines        []*Wine        `json:"wines"`
+	Ratings      []*Rating      `json:"ratings"`
+}
+
+// Game holds the settings of the archived game. Version 1 archives have no join settings,
+// so those games import with the code always active.
+type Game struct {
+	GameID                 string     `json:"gameId"`
+	GameName               string     `json:"gameName"`
+	GameCode               string     `json:"gameCode"`
+	IsRunning              bool       `json:"isRunning"`
+	AreResultsShared       bool       `json:"areResultsShared"`
+	RankingStrategy        string     `json:"rankingStrategy"`
+	TieBreakers            string     `json:"tieBreakers"`
+	MergeDuplicates        bool       `json:"mergeDuplicates"`
+	CodeDisabled           bool       `json:"codeDisabled"`
+	DisableCodeWhenStopped bool       `json:"disableCodeWhenStopped"`
+	CodeExpiresAt          *time.Time `json:"codeExpiresAt,omitempty"`
+	MaxJoins               int        `json:"maxJoins"`
+	JoinCount              int        `json:"joinCount"`
+	CreatedAt              time.Time  `json:"createdAt"`
+	UpdatedAt              time.Time  `json:"updatedAt"`
+}
+
+type Participant struct {
+	ParticipantID string    `json:"participantId"`
+	Username      string    `json:"username"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type Wine struct {
+	WineID      string    `json:"wineId"`
+	WineName    string    `json:"wineName"`
+	WineCode    string    `json:"wineCode"`
+	WineYear    int       `json:"wineYear"`
+	DuplicateOf string    `json:"duplicateOf,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type Rating struct {
+	RatingID      string    `json:"ratingId"`
+	ParticipantID string    `json:"participantId"`
+	WineID        string    `json:"wineId"`
+	SightRating   float64   `json:"sightRating"`
+	AromaRating   float64   `json:"aromaRating"`
+	TasteRating   float64   `json:"tasteRating"`
+	OverallRating float64   `json:"overallRating"`
+	Comments      string    `json:"comments"`
+	HostEntered   bool      `json:"hostEntered"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
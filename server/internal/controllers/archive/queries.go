@@ -0,0 +1,294 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
+)
+
+// Export reads the game and everything in it in a single transaction so the archive is consistent
+func (c *Controller) Export(ctx context.Context, gameID string) (*Archive, error) {
+	ctx, span := tracing.Start(ctx, "archive.Export")
+	defer span.End()
+	a := &Archive{
+		Format:     Format,
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+	}
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		if a.Game, err = exportGameTx(ctx, tx, gameID); err != nil {
+			return err
+		}
+		if a.Participants, err = exportParticipantsTx(ctx, tx, gameID); err != nil {
+			return err
+		}
+		if a.Wines, err = exportWinesTx(ctx, tx, gameID); err != nil {
+			return err
+		}
+		if a.Ratings, err = exportRatingsTx(ctx, tx, gameID); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[archive.Export] failed to export game: %w", err)
+	}
+	return a, nil
+}
+
+func exportGameTx(ctx context.Context, tx *sqlx.Tx, gameID string) (*Game, error) {
+	row := tx.QueryRowxContext(ctx, `
+		SELECT
+			game_id,
+			game_name,
+			game_code,
+			is_running,
+			are_results_shared,
+			ranking_strategy,
+			tie_breakers,
+			merge_duplicates,
+			code_disabled,
+			disable_code_when_stopped,
+			code_expires_at,
+			max_joins,
+			join_count,
+			COALESCE(created_at, NOW()),
+			COALESCE(updated_at, NOW())
+		FROM
+			game
+		WHERE game_id = $1 AND deleted_at IS NULL
+		;
+	`, gameID)
+	var g Game
+	if err := row.Scan(
+		&g.GameID,
+		&g.GameName,
+		&g.GameCode,
+		&g.IsRunning,
+		&g.AreResultsShared,
+		&g.RankingStrategy,
+		&g.TieBreakers,
+		&g.MergeDuplicates,
+		&g.CodeDisabled,
+		&g.DisableCodeWhenStopped,
+		&g.CodeExpiresAt,
+		&g.MaxJoins,
+		&g.JoinCount,
+		&g.CreatedAt,
+		&g.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("[archive.exportGameTx] no game found: %w", werrors.ErrNotFound)
+		}
+		return nil, fmt.Errorf("[archive.exportGameTx] failed to scan row: %w", err)
+	}
+	return &g, nil
+}
+
+func exportParticipantsTx(ctx context.Context, tx *sqlx.Tx, gameID string) ([]*Participant, error) {
+	rows, err := tx.QueryxContext(ctx, `
+		SELECT
+			participant_id,
+			username,
+			COALESCE(created_at, NOW()),
+			COALESCE(updated_at, NOW())
+		FROM
+			participant
+		WHERE game_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at ASC
+		;
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[archive.exportParticipantsTx] failed to query participants: %w", err)
+	}
+	defer rows.Close()
+	participants := make([]*Participant, 0)
+	for rows.Next() {
+		var p Participant
+		if err := rows.Scan(&p.ParticipantID, &p.Username, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("[archive.exportParticipantsTx] failed to scan row: %w", err)
+		}
+		participants = append(participants, &p)
+	}
+	return participants, nil
+}
+
+func exportWinesTx(ctx context.Context, tx *sqlx.Tx, gameID string) ([]*Wine, error) {
+	rows, err := tx.QueryxContext(ctx, `
+		SELECT
+			wine_id,
+			wine_name,
+			wine_code,
+			wine_year,
+			duplicate_of,
+			COALESCE(created_at, NOW()),
+			COALESCE(updated_at, NOW())
+		FROM
+			wine
+		WHERE game_id = $1 AND deleted_at IS NULL
+		ORDER BY wine_code ASC
+		;
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[archive.exportWinesTx] failed to query wines: %w", err)
+	}
+	defer rows.Close()
+	wines := make([]*Wine, 0)
+	for rows.Next() {
+		var w Wine
+		var duplicateOf sql.NullString
+		if err := rows.Scan(&w.WineID, &w.WineName, &w.WineCode, &w.WineYear, &duplicateOf, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("[archive.exportWinesTx] failed to scan row: %w", err)
+		}
+		w.DuplicateOf = duplicateOf.String
+		wines = append(wines, &w)
+	}
+	return wines, nil
+}
+
+func exportRatingsTx(ctx context.Context, tx *sqlx.Tx, gameID string) ([]*Rating, error) {
+	rows, err := tx.QueryxContext(ctx, `
+		SELECT
+			rating_id,
+			participant_id,
+			wine_id,
+			sight_rating,
+			aroma_rating,
+			taste_rating,
+			overall_rating,
+			comments,
+			host_entered,
+			COALESCE(created_at, NOW()),
+			COALESCE(updated_at, NOW())
+		FROM
+			rating
+		WHERE game_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at ASC
+		;
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[archive.exportRatingsTx] failed to query ratings: %w", err)
+	}
+	defer rows.Close()
+	ratings := make([]*Rating, 0)
+	for rows.Next() {
+		var r Rating
+		if err := rows.Scan(
+			&r.RatingID,
+			&r.ParticipantID,
+			&r.WineID,
+			&r.SightRating,
+			&r.AromaRating,
+			&r.TasteRating,
+			&r.OverallRating,
+			&r.Comments,
+			&r.HostEntered,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("[archive.exportRatingsTx] failed to scan row: %w", err)
+		}
+		ratings = append(ratings, &r)
+	}
+	return ratings, nil
+}
+
+// Import recreates the archived game with fresh UUIDs and a new game code, remapping every reference.
+// Nothing is created unless the whole archive is valid and imports cleanly.
+func (c *Controller) Import(ctx context.Context, a *Archive) (*game.Game, error) {
+	ctx, span := tracing.Start(ctx, "archive.Import")
+	defer span.End()
+	if err := validate(a); err != nil {
+		return nil, fmt.Errorf("[archive.Import] invalid archive: %w", err)
+	}
+	a = remap(a)
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		g := a.Game
+		gameCode, err := c.gameController.GenerateGameCode(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("[archive.Import] failed to generate game code: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO game (
+				game_id,
+				game_name,
+				game_code,
+				is_running,
+				are_results_shared,
+				ranking_strategy,
+				tie_breakers,
+				merge_duplicates,
+				code_disabled,
+				disable_code_when_stopped,
+				code_expires_at,
+				max_joins,
+				join_count,
+				created_at,
+				updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);
+		`, g.GameID, g.GameName, gameCode, g.IsRunning, g.AreResultsShared, g.RankingStrategy, g.TieBreakers, g.MergeDuplicates,
+			g.CodeDisabled, g.DisableCodeWhenStopped, g.CodeExpiresAt, g.MaxJoins, g.JoinCount, g.CreatedAt, g.UpdatedAt); err != nil {
+			return fmt.Errorf("[archive.Import] failed to create game: %w", err)
+		}
+		for _, p := range a.Participants {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO participant (participant_id, game_id, username, created_at, updated_at) VALUES ($1, $2, $3, $4, $5);
+			`, p.ParticipantID, g.GameID, p.Username, p.CreatedAt, p.UpdatedAt); err != nil {
+				return fmt.Errorf("[archive.Import] failed to create participant: %w", err)
+			}
+		}
+		// Duplicate markers are set once every wine exists so the archive order does not matter
+		for _, w := range a.Wines {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO wine (wine_id, game_id, wine_name, wine_code, wine_year, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7);
+			`, w.WineID, g.GameID, w.WineName, w.WineCode, w.WineYear, w.CreatedAt, w.UpdatedAt); err != nil {
+				return fmt.Errorf("[archive.Import] failed to create wine: %w", err)
+			}
+		}
+		for _, w := range a.Wines {
+			if w.DuplicateOf == "" {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE wine SET duplicate_of = $1 WHERE wine_id = $2;
+			`, w.DuplicateOf, w.WineID); err != nil {
+				return fmt.Errorf("[archive.Import] failed to mark duplicate wine: %w", err)
+			}
+		}
+		for _, r := range a.Ratings {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO rating (
+					rating_id,
+					game_id,
+					participant_id,
+					wine_id,
+					sight_rating,
+					aroma_rating,
+					taste_rating,
+					overall_rating,
+					comments,
+					host_entered,
+					created_at,
+					updated_at
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+			`, r.RatingID, g.GameID, r.ParticipantID, r.WineID, r.SightRating, r.AromaRating, r.TasteRating, r.OverallRating, r.Comments, r.HostEntered, r.CreatedAt, r.UpdatedAt); err != nil {
+				return fmt.Errorf("[archive.Import] failed to create rating: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[archive.Import] failed to import game: %w", err)
+	}
+	imported, err := c.gameController.GetSingle(ctx, a.Game.GameID)
+	if err != nil {
+		return nil, fmt.Errorf("[archive.Import] failed to get imported game: %w", err)
+	}
+	return imported, nil
+}
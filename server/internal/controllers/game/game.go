@@ -1,13 +1,16 @@
 package game
 
 import (
-	"math/rand"
-	"strconv"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
 	"strings"
-	"time"
 
 	"github.com/jacobtie/rating-party/server/internal/config"
 	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jmoiron/sqlx"
 )
 
 type Controller struct {
@@ -22,16 +25,56 @@ func NewController(cfg *config.Config, db *db.DB) *Controller {
 	}
 }
 
-func (c *Controller) GenerateGameCode() string {
-	rand := rand.New(rand.NewSource(time.Now().UnixNano()))
-	nums := [5]int{rand.Intn(36), rand.Intn(36), rand.Intn(36), rand.Intn(36), rand.Intn(36)}
-	code := [5]string{}
-	for i, num := range nums {
-		if num < 10 {
-			code[i] = strconv.Itoa(num)
-		} else {
-			code[i] = string(rune(num + 55)) // convert to capital letter, 10 => 65 (A), 35 => 90 (Z)
+// gameCodeAlphabet leaves out characters that are easily mistaken for each other, such as 0 and O or 1, I and L
+const gameCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+const (
+	gameCodeLength   = 5
+	gameCodeAttempts = 10
+)
+
+// GenerateGameCode returns a random game code that is not used by any active game
+func (c *Controller) GenerateGameCode(ctx context.Context, q sqlx.QueryerContext) (string, error) {
+	ctx, span := tracing.Start(ctx, "game.GenerateGameCode")
+	defer span.End()
+	for attempt := 0; attempt < gameCodeAttempts; attempt++ {
+		code, err := randomGameCode()
+		if err != nil {
+			return "", fmt.Errorf("[game.GenerateGameCode] failed to generate code: %w", err)
+		}
+		inUse, err := gameCodeInUse(ctx, q, code)
+		if err != nil {
+			return "", fmt.Errorf("[game.GenerateGameCode] failed to check code: %w", err)
+		}
+		if !inUse {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("[game.GenerateGameCode] no unused code found after %d attempts", gameCodeAttempts)
+}
+
+func randomGameCode() (string, error) {
+	code := make([]byte, gameCodeLength)
+	max := big.NewInt(int64(len(gameCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
 		}
+		code[i] = gameCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// NormalizeGameCode cleans up a game code typed in by a participant
+func NormalizeGameCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// splitTieBreakers converts the comma separated tie breakers column into a slice
+func splitTieBreakers(tieBreakers string) []string {
+	if tieBreakers == "" {
+		return []string{}
 	}
-	return strings.Join(code[:], "")
+	return strings.Split(tieBreakers, ",")
 }
@@ -1,9 +1,43 @@
 package game
 
+import "time"
+
 type Game struct {
-	GameID           string `json:"gameId"`
-	GameName         string `json:"gameName"`
-	GameCode         string `json:"gameCode"`
-	IsRunning        bool   `json:"isRunning"`
-	AreResultsShared bool   `json:"areResultsShared"`
+	GameID           string   `json:"gameId"`
+	GameName         string   `json:"gameName"`
+	GameCode         string   `json:"gameCode"`
+	IsRunning        bool     `json:"isRunning"`
+	AreResultsShared bool     `json:"areResultsShared"`
+	RankingStrategy  string   `json:"rankingStrategy"`
+	TieBreakers      []string `json:"tieBreakers"`
+	MergeDuplicates  bool     `json:"mergeDuplicates"`
+	JoinSettings
+	JoinCount int `json:"joinCount"`
+	// CodeVersion counts how many times the game code has been regenerated, so invites made for an old code stop working
+	CodeVersion int        `json:"-"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+}
+
+// JoinSettings control when the game code can be used to join the game
+type JoinSettings struct {
+	CodeDisabled           bool       `json:"codeDisabled"`
+	DisableCodeWhenStopped bool       `json:"disableCodeWhenStopped"`
+	CodeExpiresAt          *time.Time `json:"codeExpiresAt"`
+	// MaxJoins limits how many participants can join with the code, 0 means no limit
+	MaxJoins int `json:"maxJoins"`
+}
+
+// CodeActive reports whether new participants can currently join with the game code.
+// Participants who already joined can always sign back in.
+func (s JoinSettings) CodeActive(isRunning bool, now time.Time) bool {
+	if s.CodeDisabled {
+		return false
+	}
+	if s.DisableCodeWhenStopped && !isRunning {
+		return false
+	}
+	if s.CodeExpiresAt != nil && !now.Before(*s.CodeExpiresAt) {
+		return false
+	}
+	return true
 }
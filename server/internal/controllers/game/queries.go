@@ -5,21 +5,38 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/platform/metrics"
+	"github.com/jacobtie/rating-party/server/internal/platform/revocation"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
 	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
 )
 
 func (c *Controller) GetAll(ctx context.Context) ([]*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.GetAll")
+	defer span.End()
 	rows, err := c.db.DB.QueryxContext(ctx, `
 		SELECT
 			game_id,
 			game_name,
 			game_code,
 			is_running,
-			are_results_shared
+			are_results_shared,
+			ranking_strategy,
+			tie_breakers,
+			merge_duplicates,
+			code_disabled,
+			disable_code_when_stopped,
+			code_expires_at,
+			max_joins,
+			join_count
 		FROM
 			game
+		WHERE deleted_at IS NULL
 		;
 	`)
 	if err != nil {
@@ -32,52 +49,89 @@ func (c *Controller) GetAll(ctx context.Context) ([]*Game, error) {
 	games := make([]*Game, 0)
 	for rows.Next() {
 		var game Game
+		var tieBreakers string
 		if err := rows.Scan(
 			&game.GameID,
 			&game.GameName,
 			&game.GameCode,
 			&game.IsRunning,
 			&game.AreResultsShared,
+			&game.RankingStrategy,
+			&tieBreakers,
+			&game.MergeDuplicates,
+			&game.CodeDisabled,
+			&game.DisableCodeWhenStopped,
+			&game.CodeExpiresAt,
+			&game.MaxJoins,
+			&game.JoinCount,
 		); err != nil {
 			return nil, fmt.Errorf("[game.GetAll] failed to scan row: %w", err)
 		}
+		game.TieBreakers = splitTieBreakers(tieBreakers)
 		games = append(games, &game)
 	}
 	return games, nil
 }
 
 func (c *Controller) GetSingle(ctx context.Context, gameID string) (*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.GetSingle")
+	defer span.End()
 	row := c.db.DB.QueryRowxContext(ctx, `
 		SELECT
 			game_id,
 			game_name,
 			game_code,
 			is_running,
-			are_results_shared
+			are_results_shared,
+			ranking_strategy,
+			tie_breakers,
+			merge_duplicates,
+			code_disabled,
+			disable_code_when_stopped,
+			code_expires_at,
+			max_joins,
+			join_count,
+			code_version
 		FROM
 			game
-		WHERE game_id = $1
+		WHERE game_id = $1 AND deleted_at IS NULL
 		;
 	`, gameID)
 	var game Game
+	var tieBreakers string
 	if err := row.Scan(
 		&game.GameID,
 		&game.GameName,
 		&game.GameCode,
 		&game.IsRunning,
 		&game.AreResultsShared,
+		&game.RankingStrategy,
+		&tieBreakers,
+		&game.MergeDuplicates,
+		&game.CodeDisabled,
+		&game.DisableCodeWhenStopped,
+		&game.CodeExpiresAt,
+		&game.MaxJoins,
+		&game.JoinCount,
+		&game.CodeVersion,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("[game.GetSingle] no game found: %w", werrors.ErrNotFound)
 		}
 		return nil, fmt.Errorf("[game.GetSingle] failed to scan row: %w", err)
 	}
+	game.TieBreakers = splitTieBreakers(tieBreakers)
 	return &game, nil
 }
 
 func (c *Controller) Create(ctx context.Context, gameName string) (*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.Create")
+	defer span.End()
 	gameID := uuid.New().String()
-	gameCode := c.GenerateGameCode()
+	gameCode, err := c.GenerateGameCode(ctx, c.db)
+	if err != nil {
+		return nil, fmt.Errorf("[game.Create] failed to generate game code: %w", err)
+	}
 	if _, err := c.db.DB.ExecContext(ctx, `
 		INSERT INTO game (game_id, game_name, game_code) VALUES ($1, $2, $3);
 	`, gameID, gameName, gameCode); err != nil {
@@ -91,6 +145,8 @@ func (c *Controller) Create(ctx context.Context, gameName string) (*Game, error)
 }
 
 func (c *Controller) Update(ctx context.Context, gameID, gameName string, isRunning, areResultsShared bool) error {
+	ctx, span := tracing.Start(ctx, "game.Update")
+	defer span.End()
 	if _, err := c.GetSingle(ctx, gameID); err != nil {
 		return fmt.Errorf("[game.Update] failed to get game: %w", err)
 	}
@@ -102,15 +158,402 @@ func (c *Controller) Update(ctx context.Context, gameID, gameName string, isRunn
 	return nil
 }
 
+// UpdateRanking sets how the results are ranked. The merge duplicates setting is left as it is when mergeDuplicates is nil.
+func (c *Controller) UpdateRanking(ctx context.Context, gameID, rankingStrategy string, tieBreakers []string, mergeDuplicates *bool) error {
+	ctx, span := tracing.Start(ctx, "game.UpdateRanking")
+	defer span.End()
+	if _, err := c.GetSingle(ctx, gameID); err != nil {
+		return fmt.Errorf("[game.UpdateRanking] failed to get game: %w", err)
+	}
+	if _, err := c.db.DB.ExecContext(ctx, `
+		UPDATE game SET ranking_strategy = $1, tie_breakers = $2, merge_duplicates = COALESCE($3, merge_duplicates) WHERE game_id = $4;
+	`, rankingStrategy, strings.Join(tieBreakers, ","), mergeDuplicates, gameID); err != nil {
+		return fmt.Errorf("[game.UpdateRanking] failed to update ranking: %w", err)
+	}
+	return nil
+}
+
+// Delete soft deletes the game along with its participants, wines and ratings so it can be restored until the retention window has passed.
+// Everyone signed in to the game is signed out, and has to sign in again if the game is restored.
 func (c *Controller) Delete(ctx context.Context, gameID string) (*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.Delete")
+	defer span.End()
 	game, err := c.GetSingle(ctx, gameID)
 	if err != nil {
 		return nil, fmt.Errorf("[game.Delete] failed to get game: %w", err)
 	}
-	if _, err := c.db.DB.ExecContext(ctx, `
-		DELETE FROM game WHERE game_id = $1;
-	`, gameID); err != nil {
+	deletedAt := time.Now().UTC()
+	revoked := make(map[string]time.Time)
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		for _, table := range gameTables {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+				UPDATE %s SET deleted_at = $1 WHERE game_id = $2 AND deleted_at IS NULL;
+			`, table), deletedAt, gameID); err != nil {
+				return fmt.Errorf("[game.Delete] failed to delete from %s: %w", table, err)
+			}
+		}
+		rows, err := tx.QueryxContext(ctx, `
+			UPDATE user_session SET revoked_at = NOW() WHERE game_id = $1 AND revoked_at IS NULL RETURNING session_id, expires_at;
+		`, gameID)
+		if err != nil {
+			return fmt.Errorf("[game.Delete] failed to revoke sessions: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sessionID string
+			var expiresAt time.Time
+			if err := rows.Scan(&sessionID, &expiresAt); err != nil {
+				return fmt.Errorf("[game.Delete] failed to scan revoked session: %w", err)
+			}
+			revoked[sessionID] = expiresAt
+		}
+		return rows.Err()
+	}); err != nil {
 		return nil, fmt.Errorf("[game.Delete] failed to delete game: %w", err)
 	}
+	// Other instances pick the revocations up on their next sync
+	for sessionID, expiresAt := range revoked {
+		revocation.Revoke(sessionID, expiresAt)
+	}
+	game.DeletedAt = &deletedAt
+	return game, nil
+}
+
+// gameTables are the tables holding a game and everything in it, children first
+var gameTables = []string{"rating", "wine", "participant", "game"}
+
+// GetAllDeleted lists the soft deleted games that are still within the retention window
+func (c *Controller) GetAllDeleted(ctx context.Context) ([]*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.GetAllDeleted")
+	defer span.End()
+	rows, err := c.db.DB.QueryxContext(ctx, `
+		SELECT
+			game_id,
+			game_name,
+			game_code,
+			is_running,
+			are_results_shared,
+			ranking_strategy,
+			tie_breakers,
+			merge_duplicates,
+			code_disabled,
+			disable_code_when_stopped,
+			code_expires_at,
+			max_joins,
+			join_count,
+			deleted_at
+		FROM
+			game
+		WHERE deleted_at IS NOT NULL AND deleted_at > $1
+		ORDER BY deleted_at DESC
+		;
+	`, time.Now().UTC().Add(-c.cfg.GameRetention))
+	if err != nil {
+		return nil, fmt.Errorf("[game.GetAllDeleted] failed to query deleted games: %w", err)
+	}
+	defer rows.Close()
+	games := make([]*Game, 0)
+	for rows.Next() {
+		var game Game
+		var tieBreakers string
+		if err := rows.Scan(
+			&game.GameID,
+			&game.GameName,
+			&game.GameCode,
+			&game.IsRunning,
+			&game.AreResultsShared,
+			&game.RankingStrategy,
+			&tieBreakers,
+			&game.MergeDuplicates,
+			&game.CodeDisabled,
+			&game.DisableCodeWhenStopped,
+			&game.CodeExpiresAt,
+			&game.MaxJoins,
+			&game.JoinCount,
+			&game.DeletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("[game.GetAllDeleted] failed to scan row: %w", err)
+		}
+		game.TieBreakers = splitTieBreakers(tieBreakers)
+		games = append(games, &game)
+	}
+	return games, nil
+}
+
+// Restore brings back a soft deleted game along with the participants, wines and ratings deleted with it
+func (c *Controller) Restore(ctx context.Context, gameID string) (*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.Restore")
+	defer span.End()
+	row := c.db.DB.QueryRowxContext(ctx, `
+		SELECT deleted_at FROM game WHERE game_id = $1 AND deleted_at IS NOT NULL;
+	`, gameID)
+	var deletedAt time.Time
+	if err := row.Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("[game.Restore] no deleted game found: %w", werrors.ErrNotFound)
+		}
+		return nil, fmt.Errorf("[game.Restore] failed to scan row: %w", err)
+	}
+	if time.Since(deletedAt) > c.cfg.GameRetention {
+		return nil, fmt.Errorf("[game.Restore] game was deleted more than %s ago: %w", c.cfg.GameRetention, werrors.ErrBadRequest)
+	}
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		// Another game may have been given the same code since this one was deleted
+		var gameCode string
+		if err := tx.QueryRowxContext(ctx, `
+			SELECT game_code FROM game WHERE game_id = $1;
+		`, gameID).Scan(&gameCode); err != nil {
+			return fmt.Errorf("[game.Restore] failed to get game code: %w", err)
+		}
+		inUse, err := gameCodeInUse(ctx, tx, gameCode)
+		if err != nil {
+			return fmt.Errorf("[game.Restore] failed to check game code: %w", err)
+		}
+		if inUse {
+			if err := c.setGameCodeTx(ctx, tx, gameID); err != nil {
+				return fmt.Errorf("[game.Restore] failed to replace game code: %w", err)
+			}
+		}
+		for _, table := range gameTables {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+				UPDATE %s SET deleted_at = NULL WHERE game_id = $1 AND deleted_at = $2;
+			`, table), gameID, deletedAt); err != nil {
+				return fmt.Errorf("[game.Restore] failed to restore %s: %w", table, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[game.Restore] failed to restore game: %w", err)
+	}
+	game, err := c.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[game.Restore] failed to get restored game: %w", err)
+	}
+	return game, nil
+}
+
+// PurgeDeleted permanently removes games soft deleted before the given time, one transaction per game, and returns how many were purged
+func (c *Controller) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "game.PurgeDeleted")
+	defer span.End()
+	rows, err := c.db.DB.QueryxContext(ctx, `
+		SELECT game_id FROM game WHERE deleted_at IS NOT NULL AND deleted_at < $1;
+	`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("[game.PurgeDeleted] failed to query deleted games: %w", err)
+	}
+	gameIDs := make([]string, 0)
+	for rows.Next() {
+		var gameID string
+		if err := rows.Scan(&gameID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("[game.PurgeDeleted] failed to scan row: %w", err)
+		}
+		gameIDs = append(gameIDs, gameID)
+	}
+	rows.Close()
+	for i, gameID := range gameIDs {
+		if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+			// Duplicate markers are cleared first so wines can be deleted in any order
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE wine SET duplicate_of = NULL WHERE game_id = $1;
+			`, gameID); err != nil {
+				return fmt.Errorf("[game.PurgeDeleted] failed to clear duplicate wines: %w", err)
+			}
+			for _, table := range gameTables {
+				if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+					DELETE FROM %s WHERE game_id = $1;
+				`, table), gameID); err != nil {
+					return fmt.Errorf("[game.PurgeDeleted] failed to delete from %s: %w", table, err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return i, fmt.Errorf("[game.PurgeDeleted] failed to purge game %s: %w", gameID, err)
+		}
+	}
+	return len(gameIDs), nil
+}
+
+func gameCodeInUse(ctx context.Context, q sqlx.QueryerContext, gameCode string) (bool, error) {
+	var inUse bool
+	if err := sqlx.GetContext(ctx, q, &inUse, `
+		SELECT EXISTS (SELECT 1 FROM game WHERE game_code = $1 AND deleted_at IS NULL);
+	`, gameCode); err != nil {
+		return false, err
+	}
+	return inUse, nil
+}
+
+func (c *Controller) setGameCodeTx(ctx context.Context, tx *sqlx.Tx, gameID string) error {
+	gameCode, err := c.GenerateGameCode(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE game SET game_code = $1, code_version = code_version + 1, updated_at = NOW() WHERE game_id = $2;
+	`, gameCode, gameID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RegenerateCode gives the game a new code, for example when the old one has leaked. Participants who already joined are not affected.
+func (c *Controller) RegenerateCode(ctx context.Context, gameID string) (*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.RegenerateCode")
+	defer span.End()
+	if _, err := c.GetSingle(ctx, gameID); err != nil {
+		return nil, fmt.Errorf("[game.RegenerateCode] failed to get game: %w", err)
+	}
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		return c.setGameCodeTx(ctx, tx, gameID)
+	}); err != nil {
+		return nil, fmt.Errorf("[game.RegenerateCode] failed to set game code: %w", err)
+	}
+	game, err := c.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[game.RegenerateCode] failed to get updated game: %w", err)
+	}
 	return game, nil
 }
+
+func (c *Controller) UpdateJoinSettings(ctx context.Context, gameID string, settings JoinSettings) error {
+	ctx, span := tracing.Start(ctx, "game.UpdateJoinSettings")
+	defer span.End()
+	if settings.MaxJoins < 0 {
+		return fmt.Errorf("[game.UpdateJoinSettings] max joins cannot be negative: %w", werrors.ErrBadRequest)
+	}
+	if _, err := c.GetSingle(ctx, gameID); err != nil {
+		return fmt.Errorf("[game.UpdateJoinSettings] failed to get game: %w", err)
+	}
+	// The column has no time zone, so the expiry is stored in UTC whatever offset the client sent
+	if settings.CodeExpiresAt != nil {
+		expiresAt := settings.CodeExpiresAt.UTC()
+		settings.CodeExpiresAt = &expiresAt
+	}
+	if _, err := c.db.DB.ExecContext(ctx, `
+		UPDATE game SET code_disabled = $1, disable_code_when_stopped = $2, code_expires_at = $3, max_joins = $4 WHERE game_id = $5;
+	`, settings.CodeDisabled, settings.DisableCodeWhenStopped, settings.CodeExpiresAt, settings.MaxJoins, gameID); err != nil {
+		return fmt.Errorf("[game.UpdateJoinSettings] failed to update join settings: %w", err)
+	}
+	return nil
+}
+
+// GetJoinableByCodeTx finds the active game with the code, failing when the code cannot currently be used to join
+func (c *Controller) GetJoinableByCodeTx(ctx context.Context, tx *sqlx.Tx, gameCode string) (*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.GetJoinableByCodeTx")
+	defer span.End()
+	game, err := getJoinableTx(ctx, tx, "game_code", NormalizeGameCode(gameCode))
+	if err != nil {
+		return nil, fmt.Errorf("[game.GetJoinableByCodeTx] failed to get game: %w", err)
+	}
+	return game, nil
+}
+
+// GetJoinableByIDTx finds the active game for an invite, failing when the code the invite was made for has since been regenerated
+func (c *Controller) GetJoinableByIDTx(ctx context.Context, tx *sqlx.Tx, gameID string, codeVersion int) (*Game, error) {
+	ctx, span := tracing.Start(ctx, "game.GetJoinableByIDTx")
+	defer span.End()
+	game, err := getJoinableTx(ctx, tx, "game_id", gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[game.GetJoinableByIDTx] failed to get game: %w", err)
+	}
+	if game.CodeVersion != codeVersion {
+		return nil, fmt.Errorf("[game.GetJoinableByIDTx] game code has been regenerated: %w", werrors.ErrUnauthorized)
+	}
+	return game, nil
+}
+
+// getJoinableTx finds the active game where the column, game_code or game_id, has the value
+func getJoinableTx(ctx context.Context, tx *sqlx.Tx, column, value string) (*Game, error) {
+	row := tx.QueryRowxContext(ctx, fmt.Sprintf(`
+		SELECT
+			game_id,
+			is_running,
+			code_disabled,
+			disable_code_when_stopped,
+			code_expires_at,
+			max_joins,
+			join_count,
+			code_version
+		FROM
+			game
+		WHERE %s = $1 AND deleted_at IS NULL
+		;
+	`, column), value)
+	var game Game
+	if err := row.Scan(
+		&game.GameID,
+		&game.IsRunning,
+		&game.CodeDisabled,
+		&game.DisableCodeWhenStopped,
+		&game.CodeExpiresAt,
+		&game.MaxJoins,
+		&game.JoinCount,
+		&game.CodeVersion,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("[game.getJoinableTx] no game found: %w", werrors.ErrUnauthorized)
+		}
+		return nil, fmt.Errorf("[game.getJoinableTx] failed to scan row: %w", err)
+	}
+	return &game, nil
+}
+
+// ClaimJoinTx counts a new participant joining with the game code, failing once the join limit is reached
+func (c *Controller) ClaimJoinTx(ctx context.Context, tx *sqlx.Tx, gameID string) error {
+	ctx, span := tracing.Start(ctx, "game.ClaimJoinTx")
+	defer span.End()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE game SET join_count = join_count + 1 WHERE game_id = $1 AND (max_joins = 0 OR join_count < max_joins);
+	`, gameID)
+	if err != nil {
+		return fmt.Errorf("[game.ClaimJoinTx] failed to update join count: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("[game.ClaimJoinTx] failed to get updated rows: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("[game.ClaimJoinTx] join limit reached: %w", werrors.ErrUnauthorized)
+	}
+	return nil
+}
+
+// GetActivity counts the running games, the participants signed in to any game and the ratings in each running game
+func (c *Controller) GetActivity(ctx context.Context) (*metrics.Activity, error) {
+	ctx, span := tracing.Start(ctx, "game.GetActivity")
+	defer span.End()
+	activity := &metrics.Activity{RatingsByGame: make(map[string]int)}
+	if err := c.db.DB.QueryRowxContext(ctx, `
+		SELECT COUNT(DISTINCT participant_id) FROM user_session
+		WHERE participant_id IS NOT NULL AND revoked_at IS NULL AND expires_at > $1;
+	`, time.Now().UTC()).Scan(&activity.ActiveParticipants); err != nil {
+		return nil, fmt.Errorf("[game.GetActivity] failed to count active participants: %w", err)
+	}
+	rows, err := c.db.DB.QueryxContext(ctx, `
+		SELECT game.game_id, COUNT(rating.rating_id)
+		FROM
+			game
+			LEFT JOIN rating ON rating.game_id = game.game_id AND rating.deleted_at IS NULL
+		WHERE game.is_running = TRUE AND game.deleted_at IS NULL
+		GROUP BY game.game_id;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("[game.GetActivity] failed to count ratings: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var gameID string
+		var ratings int
+		if err := rows.Scan(&gameID, &ratings); err != nil {
+			return nil, fmt.Errorf("[game.GetActivity] failed to scan row: %w", err)
+		}
+		activity.RatingsByGame[gameID] = ratings
+		activity.RunningGames++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("[game.GetActivity] failed to read ratings: %w", err)
+	}
+	return activity, nil
+}
@@ -5,11 +5,17 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jmoiron/sqlx"
 )
 
 func (c *Controller) GetAllParticipantsByGameID(ctx context.Context, gameID string) ([]*Participant, error) {
+	ctx, span := tracing.Start(ctx, "participant.GetAllParticipantsByGameID")
+	defer span.End()
 	rows, err := c.db.DB.QueryxContext(ctx, `
-		SELECT participant_id, game_id, username FROM participant WHERE game_id = $1
+		SELECT participant_id, game_id, username FROM participant WHERE game_id = $1 AND deleted_at IS NULL
 	`, gameID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -32,3 +38,31 @@ func (c *Controller) GetAllParticipantsByGameID(ctx context.Context, gameID stri
 	}
 	return participants, nil
 }
+
+// GetOrCreateParticipantTx finds the participant with the username in the game, creating them if they have not joined yet
+func (c *Controller) GetOrCreateParticipantTx(ctx context.Context, tx *sqlx.Tx, gameID, username string) (*Participant, bool, error) {
+	ctx, span := tracing.Start(ctx, "participant.GetOrCreateParticipantTx")
+	defer span.End()
+	row := tx.QueryRowxContext(ctx, `
+		SELECT participant_id, game_id, username FROM participant WHERE game_id = $1 AND username = $2 AND deleted_at IS NULL
+	`, gameID, username)
+	var p Participant
+	err := row.Scan(&p.ParticipantID, &p.GameID, &p.Username)
+	if err == nil {
+		return &p, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("[participant.GetOrCreateParticipantTx] failed to scan participant: %w", err)
+	}
+	p = Participant{
+		ParticipantID: uuid.New().String(),
+		GameID:        gameID,
+		Username:      username,
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO participant (participant_id, username, game_id) VALUES ($1, $2, $3)
+	`, p.ParticipantID, p.Username, p.GameID); err != nil {
+		return nil, false, fmt.Errorf("[participant.GetOrCreateParticipantTx] failed to create participant: %w", err)
+	}
+	return &p, true, nil
+}
@@ -0,0 +1,233 @@
+package rating
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jacobtie/rating-party/server/internal/platform/spreadsheet"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+)
+
+// outlierCorrelation is the correlation to the consensus below which a participant is flagged as an outlier
+const outlierCorrelation = 0.2
+
+// minCorrelationWines is the minimum number of wines needed to correlate a participant with the consensus
+const minCorrelationWines = 3
+
+// GetAgreementReport measures how well each participant agrees with the rest of the group.
+// When participantID is set only that participant's entry is returned, and only once the results are shared.
+func (c *Controller) GetAgreementReport(ctx context.Context, gameID, participantID string) (*AgreementReport, error) {
+	ctx, span := tracing.Start(ctx, "rating.GetAgreementReport")
+	defer span.End()
+	if participantID != "" {
+		game, err := c.gameController.GetSingle(ctx, gameID)
+		if err != nil {
+			return nil, fmt.Errorf("[controllers.rating.GetAgreementReport] could not get game: %w", err)
+		}
+		if !game.AreResultsShared {
+			return nil, fmt.Errorf("[controllers.rating.GetAgreementReport] results are not shared: %w", werrors.ErrForbidden)
+		}
+	}
+	ratings, err := c.GetAllByGameID(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.GetAgreementReport] could not get all ratings: %w", err)
+	}
+	// scores[participantID][wineID] holds the total score the participant gave the wine
+	scores := make(map[string]map[string]float64)
+	usernames := make(map[string]string)
+	wineSet := make(map[string]bool)
+	for _, rating := range ratings {
+		if rating.isEmpty() {
+			continue
+		}
+		if _, ok := scores[rating.ParticipantID]; !ok {
+			scores[rating.ParticipantID] = make(map[string]float64)
+		}
+		scores[rating.ParticipantID][rating.WineID] = rating.TotalRating
+		usernames[rating.ParticipantID] = rating.Username
+		wineSet[rating.WineID] = true
+	}
+	wineIDs := make([]string, 0, len(wineSet))
+	for wineID := range wineSet {
+		wineIDs = append(wineIDs, wineID)
+	}
+	sort.Strings(wineIDs)
+	report := &AgreementReport{
+		Wines:        len(wineIDs),
+		Participants: make([]*RaterAgreement, 0, len(scores)),
+	}
+	if w, ok := kendallsW(scores, wineIDs); ok {
+		roundedW := roundStatistic(w)
+		report.KendallsW = &roundedW
+	}
+	for raterID, raterScores := range scores {
+		totals := make([]float64, 0, len(raterScores))
+		for _, score := range raterScores {
+			totals = append(totals, score)
+		}
+		sorted := sortedCopy(totals)
+		agreement := &RaterAgreement{
+			ParticipantID:     raterID,
+			Username:          usernames[raterID],
+			RatingCount:       len(totals),
+			MeanScore:         roundScore(mean(totals)),
+			StandardDeviation: roundScore(standardDeviation(totals)),
+			MinScore:          sorted[0],
+			MaxScore:          sorted[len(sorted)-1],
+		}
+		if correlation, ok := consensusCorrelation(scores, raterID, wineIDs); ok {
+			roundedCorrelation := roundStatistic(correlation)
+			agreement.Correlation = &roundedCorrelation
+			agreement.IsOutlier = correlation < outlierCorrelation
+		}
+		report.Participants = append(report.Participants, agreement)
+	}
+	sort.Slice(report.Participants, func(i, j int) bool {
+		return report.Participants[i].Username < report.Participants[j].Username
+	})
+	if participantID != "" {
+		filtered := make([]*RaterAgreement, 0, 1)
+		for _, agreement := range report.Participants {
+			if agreement.ParticipantID == participantID {
+				filtered = append(filtered, agreement)
+			}
+		}
+		report.Participants = filtered
+	}
+	return report, nil
+}
+
+// consensusCorrelation returns the Spearman correlation between a participant's scores and
+// the mean scores of every other participant, over the wines both have rated
+func consensusCorrelation(scores map[string]map[string]float64, raterID string, wineIDs []string) (float64, bool) {
+	own := make([]float64, 0)
+	consensus := make([]float64, 0)
+	for _, wineID := range wineIDs {
+		score, ok := scores[raterID][wineID]
+		if !ok {
+			continue
+		}
+		others := make([]float64, 0)
+		for otherID, otherScores := range scores {
+			if otherID == raterID {
+				continue
+			}
+			if otherScore, ok := otherScores[wineID]; ok {
+				others = append(others, otherScore)
+			}
+		}
+		if len(others) == 0 {
+			continue
+		}
+		own = append(own, score)
+		consensus = append(consensus, mean(others))
+	}
+	if len(own) < minCorrelationWines {
+		return 0, false
+	}
+	return pearson(averageRanks(own), averageRanks(consensus))
+}
+
+// kendallsW returns the tie corrected Kendall's coefficient of concordance for the participants who rated every wine
+func kendallsW(scores map[string]map[string]float64, wineIDs []string) (float64, bool) {
+	n := len(wineIDs)
+	if n < 2 {
+		return 0, false
+	}
+	rankSums := make([]float64, n)
+	m := 0
+	tieCorrection := 0.0
+	for _, raterScores := range scores {
+		if len(raterScores) != n {
+			continue
+		}
+		block := make([]float64, n)
+		for i, wineID := range wineIDs {
+			block[i] = raterScores[wineID]
+		}
+		for i, rank := range averageRanks(block) {
+			rankSums[i] += rank
+		}
+		for _, t := range tieGroupSizes(block) {
+			tieCorrection += float64(t*t*t - t)
+		}
+		m++
+	}
+	if m < 2 {
+		return 0, false
+	}
+	meanRankSum := mean(rankSums)
+	s := 0.0
+	for _, rankSum := range rankSums {
+		s += (rankSum - meanRankSum) * (rankSum - meanRankSum)
+	}
+	denominator := float64(m*m)*float64(n*n*n-n) - float64(m)*tieCorrection
+	if denominator <= 0 {
+		return 0, false
+	}
+	return 12 * s / denominator, true
+}
+
+// tieGroupSizes returns the size of every group of tied values
+func tieGroupSizes(values []float64) []int {
+	counts := make(map[float64]int)
+	for _, value := range values {
+		counts[value]++
+	}
+	sizes := make([]int, 0)
+	for _, count := range counts {
+		if count > 1 {
+			sizes = append(sizes, count)
+		}
+	}
+	return sizes
+}
+
+func pearson(a, b []float64) (float64, bool) {
+	meanA := mean(a)
+	meanB := mean(b)
+	covariance, varianceA, varianceB := 0.0, 0.0, 0.0
+	for i := range a {
+		covariance += (a[i] - meanA) * (b[i] - meanB)
+		varianceA += (a[i] - meanA) * (a[i] - meanA)
+		varianceB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	if varianceA == 0 || varianceB == 0 {
+		return 0, false
+	}
+	return covariance / math.Sqrt(varianceA*varianceB), true
+}
+
+// sheet renders the report with one row per participant
+func (r *AgreementReport) sheet() *spreadsheet.Sheet {
+	var kendallsW any
+	if r.KendallsW != nil {
+		kendallsW = *r.KendallsW
+	}
+	sheet := &spreadsheet.Sheet{
+		Name:   "Agreement",
+		Header: []string{"Username", "Ratings", "Correlation", "Mean Score", "Standard Deviation", "Min Score", "Max Score", "Outlier", "Kendall's W"},
+		Rows:   make([][]any, 0, len(r.Participants)),
+	}
+	for _, p := range r.Participants {
+		var correlation any
+		if p.Correlation != nil {
+			correlation = *p.Correlation
+		}
+		sheet.Rows = append(sheet.Rows, []any{
+			p.Username,
+			p.RatingCount,
+			correlation,
+			p.MeanScore,
+			p.StandardDeviation,
+			p.MinScore,
+			p.MaxScore,
+			p.IsOutlier,
+			kendallsW,
+		})
+	}
+	return sheet
+}
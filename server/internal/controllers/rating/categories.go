@@ -0,0 +1,68 @@
+package rating
+
+import (
+	"math"
+	"sort"
+)
+
+// histogramBucketWidth is the width of each bucket in a total score histogram
+const histogramBucketWidth = 2
+
+// categoryMean returns the average score the wine was given in the category
+func (ws *wineScores) categoryMean(key string) float64 {
+	return mean(ws.categories[key])
+}
+
+// histogram counts the total scores in buckets of histogramBucketWidth, with the maximum score in the last bucket
+func histogram(totals []float64) []int {
+	buckets := make([]int, MaxTotal/histogramBucketWidth)
+	for _, total := range totals {
+		bucket := int(math.Floor(total / histogramBucketWidth))
+		if bucket < 0 {
+			bucket = 0
+		}
+		if bucket >= len(buckets) {
+			bucket = len(buckets) - 1
+		}
+		buckets[bucket]++
+	}
+	return buckets
+}
+
+// buildCategoryLeaderboards ranks the wines separately on each scorecard category,
+// with wines on the same average sharing a rank
+func buildCategoryLeaderboards(results []*wineResult) []*CategoryLeaderboard {
+	leaderboards := make([]*CategoryLeaderboard, 0, len(Categories))
+	for _, category := range Categories {
+		leaderboard := &CategoryLeaderboard{
+			Category: category,
+			Wines:    make([]*CategoryScore, 0, len(results)),
+		}
+		// Wines are ordered by their unrounded averages, which are only rounded for display
+		averages := make(map[*CategoryScore]float64, len(results))
+		for _, result := range results {
+			scores := result.scores.categories[category.Key]
+			score := &CategoryScore{
+				WineID:   result.wine.WineID,
+				WineName: result.wine.WineName,
+				WineCode: result.wine.WineCode,
+				WineYear: result.wine.WineYear,
+				Avg:      roundScore(mean(scores)),
+				StdDev:   roundScore(standardDeviation(scores)),
+			}
+			averages[score] = mean(scores)
+			leaderboard.Wines = append(leaderboard.Wines, score)
+		}
+		sort.SliceStable(leaderboard.Wines, func(i, j int) bool {
+			return compareScores(averages[leaderboard.Wines[i]], averages[leaderboard.Wines[j]]) > 0
+		})
+		for i, score := range leaderboard.Wines {
+			score.Rank = i + 1
+			if i > 0 && compareScores(averages[leaderboard.Wines[i-1]], averages[score]) == 0 {
+				score.Rank = leaderboard.Wines[i-1].Rank
+			}
+		}
+		leaderboards = append(leaderboards, leaderboard)
+	}
+	return leaderboards
+}
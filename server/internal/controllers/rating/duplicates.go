@@ -0,0 +1,116 @@
+package rating
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+)
+
+// GetDuplicateReport compares each participant's scores for wines that were poured twice under different codes
+func (c *Controller) GetDuplicateReport(ctx context.Context, gameID string) (*DuplicateReport, error) {
+	ctx, span := tracing.Start(ctx, "rating.GetDuplicateReport")
+	defer span.End()
+	wines, err := c.wineController.GetAllWines(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.GetDuplicateReport] could not get all wines: %w", err)
+	}
+	ratings, err := c.GetAllByGameID(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.GetDuplicateReport] could not get all ratings: %w", err)
+	}
+	// ratingsByWine[wineID][participantID] holds the participant's rating of the wine
+	ratingsByWine := make(map[string]map[string]*Rating)
+	for _, rating := range ratings {
+		if rating.isEmpty() {
+			continue
+		}
+		if _, ok := ratingsByWine[rating.WineID]; !ok {
+			ratingsByWine[rating.WineID] = make(map[string]*Rating)
+		}
+		ratingsByWine[rating.WineID][rating.ParticipantID] = rating
+	}
+	winesByID := make(map[string]string, len(wines))
+	for _, wine := range wines {
+		winesByID[wine.WineID] = wine.WineCode
+	}
+	report := &DuplicateReport{
+		Pairs:        make([]*DuplicatePair, 0),
+		Participants: make([]*ParticipantConsistency, 0),
+	}
+	consistency := make(map[string]*ParticipantConsistency)
+	differenceSums := make(map[string]float64)
+	for _, duplicate := range wines {
+		if duplicate.DuplicateOfWineID == "" {
+			continue
+		}
+		pair := &DuplicatePair{
+			WineID:            duplicate.DuplicateOfWineID,
+			WineCode:          winesByID[duplicate.DuplicateOfWineID],
+			DuplicateWineID:   duplicate.WineID,
+			DuplicateWineCode: duplicate.WineCode,
+			WineName:          duplicate.WineName,
+			Differences:       make([]*DuplicateDifference, 0),
+		}
+		for participantID, duplicateRating := range ratingsByWine[duplicate.WineID] {
+			original, ok := ratingsByWine[duplicate.DuplicateOfWineID][participantID]
+			if !ok {
+				continue
+			}
+			difference := roundScore(original.TotalRating - duplicateRating.TotalRating)
+			pair.Differences = append(pair.Differences, &DuplicateDifference{
+				ParticipantID:  participantID,
+				Username:       original.Username,
+				Score:          original.TotalRating,
+				DuplicateScore: duplicateRating.TotalRating,
+				Difference:     difference,
+			})
+			if _, ok := consistency[participantID]; !ok {
+				consistency[participantID] = &ParticipantConsistency{
+					ParticipantID: participantID,
+					Username:      original.Username,
+				}
+			}
+			participantConsistency := consistency[participantID]
+			participantConsistency.Pairs++
+			participantConsistency.MaxAbsoluteDifference = math.Max(participantConsistency.MaxAbsoluteDifference, math.Abs(difference))
+			differenceSums[participantID] += math.Abs(difference)
+		}
+		sort.Slice(pair.Differences, func(i, j int) bool {
+			return pair.Differences[i].Username < pair.Differences[j].Username
+		})
+		report.Pairs = append(report.Pairs, pair)
+	}
+	for participantID, participantConsistency := range consistency {
+		participantConsistency.MeanAbsoluteDifference = roundScore(differenceSums[participantID] / float64(participantConsistency.Pairs))
+		report.Participants = append(report.Participants, participantConsistency)
+	}
+	// Most consistent participants first
+	sort.Slice(report.Participants, func(i, j int) bool {
+		a, b := report.Participants[i], report.Participants[j]
+		if a.MeanAbsoluteDifference != b.MeanAbsoluteDifference {
+			return a.MeanAbsoluteDifference < b.MeanAbsoluteDifference
+		}
+		return a.Username < b.Username
+	})
+	return report, nil
+}
+
+// mergeRatings averages two ratings a participant gave to duplicate pours of the same wine
+func mergeRatings(a, b *Rating) *Rating {
+	merged := *a
+	merged.SightRating = (a.SightRating + b.SightRating) / 2
+	merged.AromaRating = (a.AromaRating + b.AromaRating) / 2
+	merged.TasteRating = (a.TasteRating + b.TasteRating) / 2
+	merged.OverallRating = (a.OverallRating + b.OverallRating) / 2
+	merged.TotalRating = (a.TotalRating + b.TotalRating) / 2
+	if b.Comments != "" {
+		if merged.Comments != "" {
+			merged.Comments += "\n"
+		}
+		merged.Comments += b.Comments
+	}
+	return &merged
+}
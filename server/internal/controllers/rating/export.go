@@ -0,0 +1,175 @@
+package rating
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jacobtie/rating-party/server/internal/platform/spreadsheet"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+)
+
+// ExportResults renders the ranked results as a spreadsheet, with the same username visibility as GetRatingsResult
+func (c *Controller) ExportResults(ctx context.Context, gameID string, includeUsernames bool, format string) (*spreadsheet.File, error) {
+	ctx, span := tracing.Start(ctx, "rating.ExportResults")
+	defer span.End()
+	if !spreadsheet.IsValidFormat(format) {
+		return nil, fmt.Errorf("[controllers.rating.ExportResults] unknown format %q: %w", format, werrors.ErrBadRequest)
+	}
+	game, err := c.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportResults] could not get game: %w", err)
+	}
+	result, err := c.GetRatingsResult(ctx, gameID, includeUsernames, ResultOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportResults] could not get ratings result: %w", err)
+	}
+	usernames := make([]string, 0)
+	if includeUsernames {
+		participants, err := c.participantController.GetAllParticipantsByGameID(ctx, gameID)
+		if err != nil {
+			return nil, fmt.Errorf("[controllers.rating.ExportResults] could not get all participants: %w", err)
+		}
+		for _, participant := range participants {
+			usernames = append(usernames, participant.Username)
+		}
+	}
+	// categoryAvgs[category][wineID] holds the wine's average for the category
+	categoryAvgs := make(map[string]map[string]float64)
+	for _, leaderboard := range result.Categories {
+		categoryAvgs[leaderboard.Key] = make(map[string]float64)
+		for _, score := range leaderboard.Wines {
+			categoryAvgs[leaderboard.Key][score.WineID] = score.Avg
+		}
+	}
+	header := []string{"Rank", "Wine Code", "Wine Name", "Wine Year", "Score", "Average", "Std Dev", "Ratings"}
+	for _, category := range Categories {
+		header = append(header, category.Label)
+	}
+	header = append(header, usernames...)
+	results := &spreadsheet.Sheet{
+		Name:   "Results",
+		Header: header,
+		Rows:   make([][]any, 0, len(result.Rows)),
+	}
+	for _, row := range result.Rows {
+		values := []any{row["rank"], row["wineCode"], row["wineName"], row["wineYear"], row["score"], row["avg"], row["stdDev"], row["ratingCount"]}
+		for _, category := range Categories {
+			values = append(values, categoryAvgs[category.Key][rowWineID(row)])
+		}
+		scores := rowScores(row)
+		for _, username := range usernames {
+			values = append(values, scores[username])
+		}
+		results.Rows = append(results.Rows, values)
+	}
+	categories := &spreadsheet.Sheet{
+		Name:   "Categories",
+		Header: []string{"Category", "Rank", "Wine Code", "Wine Name", "Wine Year", "Average", "Std Dev"},
+		Rows:   make([][]any, 0),
+	}
+	for _, leaderboard := range result.Categories {
+		for _, score := range leaderboard.Wines {
+			categories.Rows = append(categories.Rows, []any{leaderboard.Label, score.Rank, score.WineCode, score.WineName, score.WineYear, score.Avg, score.StdDev})
+		}
+	}
+	file, err := spreadsheet.NewFile(format, game.GameName, "results", results, categories)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportResults] could not render export: %w", err)
+	}
+	return file, nil
+}
+
+// ExportRatings renders the individual ratings as a spreadsheet. When participantID is set only that
+// participant's ratings are exported, and wine names are only included once the results are shared.
+func (c *Controller) ExportRatings(ctx context.Context, gameID, participantID, format string) (*spreadsheet.File, error) {
+	ctx, span := tracing.Start(ctx, "rating.ExportRatings")
+	defer span.End()
+	if !spreadsheet.IsValidFormat(format) {
+		return nil, fmt.Errorf("[controllers.rating.ExportRatings] unknown format %q: %w", format, werrors.ErrBadRequest)
+	}
+	game, err := c.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportRatings] could not get game: %w", err)
+	}
+	wines, err := c.wineController.GetAllWines(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportRatings] could not get all wines: %w", err)
+	}
+	var ratings []*Rating
+	if participantID == "" {
+		ratings, err = c.GetAllByGameID(ctx, gameID)
+	} else {
+		ratings, err = c.GetAllByGameIDAndParticipantID(ctx, gameID, participantID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportRatings] could not get ratings: %w", err)
+	}
+	isAdmin := participantID == ""
+	revealWines := isAdmin || game.AreResultsShared
+	header := make([]string, 0)
+	if isAdmin {
+		header = append(header, "Username")
+	}
+	header = append(header, "Wine Code")
+	if revealWines {
+		header = append(header, "Wine Name", "Wine Year")
+	}
+	for _, category := range Categories {
+		header = append(header, category.Label)
+	}
+	header = append(header, "Total", "Comments")
+	sheet := &spreadsheet.Sheet{
+		Name:   "Ratings",
+		Header: header,
+		Rows:   make([][]any, 0, len(ratings)),
+	}
+	// Wines are already ordered by code, so rows follow the serving order
+	for _, wine := range wines {
+		for _, rating := range ratings {
+			if rating.WineID != wine.WineID || rating.isEmpty() {
+				continue
+			}
+			values := make([]any, 0, len(header))
+			if isAdmin {
+				values = append(values, rating.Username)
+			}
+			values = append(values, wine.WineCode)
+			if revealWines {
+				values = append(values, wine.WineName, wine.WineYear)
+			}
+			for _, category := range Categories {
+				values = append(values, rating.categoryScore(category.Key))
+			}
+			values = append(values, rating.TotalRating, rating.Comments)
+			sheet.Rows = append(sheet.Rows, values)
+		}
+	}
+	file, err := spreadsheet.NewFile(format, game.GameName, "ratings", sheet)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportRatings] could not render export: %w", err)
+	}
+	return file, nil
+}
+
+// ExportAgreementReport renders the agreement report as a spreadsheet, see GetAgreementReport for visibility
+func (c *Controller) ExportAgreementReport(ctx context.Context, gameID, participantID, format string) (*spreadsheet.File, error) {
+	ctx, span := tracing.Start(ctx, "rating.ExportAgreementReport")
+	defer span.End()
+	if !spreadsheet.IsValidFormat(format) {
+		return nil, fmt.Errorf("[controllers.rating.ExportAgreementReport] unknown format %q: %w", format, werrors.ErrBadRequest)
+	}
+	game, err := c.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportAgreementReport] could not get game: %w", err)
+	}
+	report, err := c.GetAgreementReport(ctx, gameID, participantID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportAgreementReport] could not get agreement report: %w", err)
+	}
+	file, err := spreadsheet.NewFile(format, game.GameName, "agreement", report.sheet())
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ExportAgreementReport] could not render export: %w", err)
+	}
+	return file, nil
+}
@@ -12,4 +12,147 @@ type Rating struct {
 	OverallRating float64 `json:"overallRating"`
 	TotalRating   float64 `json:"totalRating"`
 	Comments      string  `json:"comments"`
+	HostEntered   bool    `json:"hostEntered"`
+}
+
+// isEmpty reports whether the rating was never filled in by the participant
+func (r *Rating) isEmpty() bool {
+	return r.SightRating == 0 &&
+		r.AromaRating == 0 &&
+		r.TasteRating == 0 &&
+		r.OverallRating == 0 &&
+		r.Comments == ""
+}
+
+type RatingsResult struct {
+	RankingStrategy string                 `json:"rankingStrategy"`
+	TieBreakers     []string               `json:"tieBreakers"`
+	MergeDuplicates bool                   `json:"mergeDuplicates"`
+	Rows            []map[string]any       `json:"rows"`
+	Categories      []*CategoryLeaderboard `json:"categories"`
+	Statistics      *ResultStatistics      `json:"statistics,omitempty"`
+}
+
+type CategoryLeaderboard struct {
+	Category
+	Wines []*CategoryScore `json:"wines"`
+}
+
+type CategoryScore struct {
+	WineID   string  `json:"wineId"`
+	WineName string  `json:"wineName"`
+	WineCode string  `json:"wineCode"`
+	WineYear int     `json:"wineYear"`
+	Avg      float64 `json:"avg"`
+	StdDev   float64 `json:"stdDev"`
+	Rank     int     `json:"rank"`
+}
+
+type WineDetail struct {
+	WineID     string              `json:"wineId"`
+	WineName   string              `json:"wineName"`
+	WineCode   string              `json:"wineCode"`
+	WineYear   int                 `json:"wineYear"`
+	Avg        float64             `json:"avg"`
+	StdDev     float64             `json:"stdDev"`
+	Histogram  []int               `json:"histogram"`
+	Categories []*CategorySummary  `json:"categories"`
+	Ratings    []*WineDetailRating `json:"ratings"`
+}
+
+type CategorySummary struct {
+	Category
+	Avg    float64 `json:"avg"`
+	StdDev float64 `json:"stdDev"`
+}
+
+type WineDetailRating struct {
+	Username      string  `json:"username,omitempty"`
+	SightRating   float64 `json:"sightRating"`
+	AromaRating   float64 `json:"aromaRating"`
+	TasteRating   float64 `json:"tasteRating"`
+	OverallRating float64 `json:"overallRating"`
+	TotalRating   float64 `json:"totalRating"`
+	Comments      string  `json:"comments"`
+}
+
+type ResultOptions struct {
+	IncludeStatistics bool
+	PostHocMethod     PostHocMethod
+}
+
+type ResultStatistics struct {
+	Alpha    float64         `json:"alpha"`
+	Friedman *FriedmanResult `json:"friedman,omitempty"`
+	PostHoc  *PostHocResult  `json:"postHoc,omitempty"`
+}
+
+type FriedmanResult struct {
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom int     `json:"degreesOfFreedom"`
+	PValue           float64 `json:"pValue"`
+	Participants     int     `json:"participants"`
+	Significant      bool    `json:"significant"`
+}
+
+type PostHocResult struct {
+	Method             string                `json:"method"`
+	CriticalDifference *float64              `json:"criticalDifference,omitempty"`
+	Comparisons        []*PairwiseComparison `json:"comparisons"`
+}
+
+type PairwiseComparison struct {
+	WineAID        string   `json:"wineAId"`
+	WineBID        string   `json:"wineBId"`
+	RankDifference *float64 `json:"rankDifference,omitempty"`
+	PValue         *float64 `json:"pValue,omitempty"`
+	Significant    bool     `json:"significant"`
+}
+
+type AgreementReport struct {
+	KendallsW    *float64          `json:"kendallsW,omitempty"`
+	Wines        int               `json:"wines"`
+	Participants []*RaterAgreement `json:"participants"`
+}
+
+type RaterAgreement struct {
+	ParticipantID     string   `json:"participantId"`
+	Username          string   `json:"username"`
+	RatingCount       int      `json:"ratingCount"`
+	Correlation       *float64 `json:"correlation,omitempty"`
+	MeanScore         float64  `json:"meanScore"`
+	StandardDeviation float64  `json:"standardDeviation"`
+	MinScore          float64  `json:"minScore"`
+	MaxScore          float64  `json:"maxScore"`
+	IsOutlier         bool     `json:"isOutlier"`
+}
+
+type DuplicateReport struct {
+	Pairs        []*DuplicatePair          `json:"pairs"`
+	Participants []*ParticipantConsistency `json:"participants"`
+}
+
+type DuplicatePair struct {
+	WineID            string                 `json:"wineId"`
+	WineCode          string                 `json:"wineCode"`
+	WineName          string                 `json:"wineName"`
+	DuplicateWineID   string                 `json:"duplicateWineId"`
+	DuplicateWineCode string                 `json:"duplicateWineCode"`
+	Differences       []*DuplicateDifference `json:"differences"`
+}
+
+type DuplicateDifference struct {
+	ParticipantID  string  `json:"participantId"`
+	Username       string  `json:"username"`
+	Score          float64 `json:"score"`
+	DuplicateScore float64 `json:"duplicateScore"`
+	Difference     float64 `json:"difference"`
+}
+
+type ParticipantConsistency struct {
+	ParticipantID          string  `json:"participantId"`
+	Username               string  `json:"username"`
+	Pairs                  int     `json:"pairs"`
+	MeanAbsoluteDifference float64 `json:"meanAbsoluteDifference"`
+	MaxAbsoluteDifference  float64 `json:"maxAbsoluteDifference"`
 }
@@ -0,0 +1,175 @@
+package rating
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
+	"github.com/jacobtie/rating-party/server/internal/platform/pdfdoc"
+	"github.com/jacobtie/rating-party/server/internal/platform/spreadsheet"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+)
+
+const (
+	scorecardRowHeight = 12.0
+	scorecardQRSize    = 32.0
+	labelColumns       = 2
+	labelRows          = 4
+	labelWidth         = 90.0
+	labelHeight        = 62.0
+)
+
+// Scorecards renders a printable PDF with a blind scorecard for every participant followed by a numbered
+// bottle sleeve label for every wine. Extra unnamed scorecards are added for participants who have not joined yet.
+// Every scorecard lists the wines in the same fixed wine code order, which is the order they are poured in.
+func (c *Controller) Scorecards(ctx context.Context, gameID string, blankCards int) ([]byte, string, error) {
+	ctx, span := tracing.Start(ctx, "rating.Scorecards")
+	defer span.End()
+	game, err := c.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, "", fmt.Errorf("[controllers.rating.Scorecards] could not get game: %w", err)
+	}
+	participants, err := c.participantController.GetAllParticipantsByGameID(ctx, gameID)
+	if err != nil {
+		return nil, "", fmt.Errorf("[controllers.rating.Scorecards] could not get all participants: %w", err)
+	}
+	// Wines are served in wine code order
+	wines, err := c.wineController.GetAllWines(ctx, gameID)
+	if err != nil {
+		return nil, "", fmt.Errorf("[controllers.rating.Scorecards] could not get all wines: %w", err)
+	}
+	usernames := make([]string, 0, len(participants)+blankCards)
+	for _, participant := range participants {
+		usernames = append(usernames, participant.Username)
+	}
+	for i := 0; i < blankCards; i++ {
+		usernames = append(usernames, "")
+	}
+	if len(usernames) == 0 {
+		usernames = append(usernames, "")
+	}
+
+	doc := pdfdoc.New(fmt.Sprintf("%s scorecards", game.GameName))
+	for i, username := range usernames {
+		if i > 0 {
+			doc.AddPage()
+		}
+		if err := c.writeScorecard(doc, game, wines, username); err != nil {
+			return nil, "", fmt.Errorf("[controllers.rating.Scorecards] could not write scorecard: %w", err)
+		}
+	}
+	if len(wines) > 0 {
+		writeBottleLabels(doc, game, wines)
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		return nil, "", fmt.Errorf("[controllers.rating.Scorecards] could not render scorecards: %w", err)
+	}
+	return data, spreadsheet.Filename(game.GameName, "scorecards", "pdf"), nil
+}
+
+// writeScorecard draws one scorecard with a row for each wine in wine code order. The order is not shuffled
+// per card because only the codes are printed, so it gives nothing away and guests can follow the pour.
+func (c *Controller) writeScorecard(doc *pdfdoc.Document, game *game.Game, wines []*wine.Wine, username string) error {
+	pdf := doc.PDF()
+	left, top, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	qrX := pageWidth - right - scorecardQRSize
+	if err := doc.QRCode(c.ratingPageURL(game.GameCode, username), qrX, top, scorecardQRSize); err != nil {
+		return fmt.Errorf("[controllers.rating.writeScorecard] could not draw qr code: %w", err)
+	}
+	doc.SetFont("", 7)
+	pdf.SetXY(qrX, top+scorecardQRSize)
+	pdf.CellFormat(scorecardQRSize, 4, "Scan to rate online", "", 0, "C", false, 0, "")
+
+	// Keep the heading text clear of the QR code
+	pdf.SetRightMargin(right + scorecardQRSize + 5)
+	pdf.SetXY(left, top)
+	doc.Title(game.GameName)
+	if username != "" {
+		doc.Subtitle(fmt.Sprintf("Scorecard for %s", username))
+	} else {
+		doc.Subtitle("Name: ______________________________")
+	}
+	doc.Paragraph(fmt.Sprintf("Party code: %s", game.GameCode))
+	pdf.SetRightMargin(right)
+	if y := top + scorecardQRSize + 8; pdf.GetY() < y {
+		pdf.SetY(y)
+	}
+
+	ranges := make([]string, 0, len(Categories))
+	header := []string{"Code"}
+	widths := []float64{18}
+	for _, category := range Categories {
+		header = append(header, fmt.Sprintf("%s /%s", category.Label, formatScore(category.Max)))
+		widths = append(widths, 22)
+		ranges = append(ranges, fmt.Sprintf("%s 0-%s", category.Label, formatScore(category.Max)))
+	}
+	header = append(header, fmt.Sprintf("Total /%d", MaxTotal), "Comments")
+	widths = append(widths, 22)
+	widths = append(widths, doc.ContentWidth()-sum(widths))
+	doc.Paragraph(fmt.Sprintf("Score each wine on %s for a total out of %d.", strings.Join(ranges, ", "), MaxTotal))
+
+	rows := make([][]string, 0, len(wines))
+	for _, wine := range wines {
+		row := make([]string, len(header))
+		row[0] = wine.WineCode
+		rows = append(rows, row)
+	}
+	doc.FormTable(header, widths, rows, scorecardRowHeight)
+	return nil
+}
+
+// writeBottleLabels lays out a grid of cut out sleeve labels, one per wine, showing only the wine code
+func writeBottleLabels(doc *pdfdoc.Document, game *game.Game, wines []*wine.Wine) {
+	pdf := doc.PDF()
+	left, top, _, _ := pdf.GetMargins()
+	perPage := labelColumns * labelRows
+	for i, wine := range wines {
+		if i%perPage == 0 {
+			doc.AddPage()
+		}
+		position := i % perPage
+		x := left + float64(position%labelColumns)*labelWidth
+		y := top + float64(position/labelColumns)*labelHeight
+
+		pdf.SetDrawColor(160, 160, 160)
+		pdf.SetDashPattern([]float64{2, 2}, 0)
+		pdf.Rect(x, y, labelWidth, labelHeight, "D")
+		pdf.SetDashPattern([]float64{}, 0)
+		pdf.SetDrawColor(0, 0, 0)
+
+		doc.SetFont("B", 48)
+		pdf.SetXY(x, y+12)
+		pdf.CellFormat(labelWidth, 24, doc.Tr(wine.WineCode), "", 0, "C", false, 0, "")
+		doc.SetFont("", 9)
+		pdf.SetTextColor(96, 96, 96)
+		pdf.SetXY(x, y+labelHeight-14)
+		pdf.CellFormat(labelWidth, 5, doc.Tr(fmt.Sprintf("Bottle %d of %d", i+1, len(wines))), "", 0, "C", false, 0, "")
+		pdf.SetXY(x, y+labelHeight-9)
+		pdf.CellFormat(labelWidth, 5, doc.Tr(game.GameName), "", 0, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+}
+
+// ratingPageURL links to the sign in page with the party code and username filled in
+func (c *Controller) ratingPageURL(gameCode, username string) string {
+	query := url.Values{}
+	query.Set("code", gameCode)
+	if username != "" {
+		query.Set("username", username)
+	}
+	return fmt.Sprintf("%s/?%s", strings.TrimRight(c.cfg.Web.PublicURL, "/"), query.Encode())
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
@@ -0,0 +1,261 @@
+package rating
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
+)
+
+// ProxyRating is a rating the host enters on behalf of a participant, usually copied from a paper scorecard
+type ProxyRating struct {
+	Username      string  `json:"username"`
+	WineCode      string  `json:"wineCode"`
+	SightRating   float64 `json:"sightRating"`
+	AromaRating   float64 `json:"aromaRating"`
+	TasteRating   float64 `json:"tasteRating"`
+	OverallRating float64 `json:"overallRating"`
+	Comments      string  `json:"comments"`
+}
+
+type ImportResult struct {
+	Imported            int      `json:"imported"`
+	CreatedParticipants []string `json:"createdParticipants"`
+}
+
+// ImportRatings saves ratings entered by the host, creating any participants who have not joined the game yet.
+// Every rating is marked as host entered, and nothing is saved unless every rating is valid.
+func (c *Controller) ImportRatings(ctx context.Context, gameID string, entries []*ProxyRating) (*ImportResult, error) {
+	ctx, span := tracing.Start(ctx, "rating.ImportRatings")
+	defer span.End()
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("[controllers.rating.ImportRatings] no ratings to import: %w", werrors.ErrBadRequest)
+	}
+	if _, err := c.gameController.GetSingle(ctx, gameID); err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ImportRatings] could not get game: %w", err)
+	}
+	wines, err := c.wineController.GetAllWines(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ImportRatings] could not get all wines: %w", err)
+	}
+	// Handwritten codes are matched regardless of case
+	wineIDs := make(map[string]string, len(wines))
+	for _, wine := range wines {
+		wineIDs[strings.ToLower(wine.WineCode)] = wine.WineID
+	}
+	ratings := make([]*Rating, 0, len(entries))
+	usernames := make([]string, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		username := strings.TrimSpace(entry.Username)
+		if username == "" {
+			return nil, fmt.Errorf("[controllers.rating.ImportRatings] rating %d has no username: %w", i+1, werrors.ErrBadRequest)
+		}
+		wineID, ok := wineIDs[strings.ToLower(strings.TrimSpace(entry.WineCode))]
+		if !ok {
+			return nil, fmt.Errorf("[controllers.rating.ImportRatings] rating %d has unknown wine code %q: %w", i+1, entry.WineCode, werrors.ErrBadRequest)
+		}
+		key := username + "\x00" + wineID
+		if seen[key] {
+			return nil, fmt.Errorf("[controllers.rating.ImportRatings] rating %d rates wine %q for %s more than once: %w", i+1, entry.WineCode, username, werrors.ErrBadRequest)
+		}
+		seen[key] = true
+		rating := &Rating{
+			GameID:        gameID,
+			WineID:        wineID,
+			SightRating:   entry.SightRating,
+			AromaRating:   entry.AromaRating,
+			TasteRating:   entry.TasteRating,
+			OverallRating: entry.OverallRating,
+			Comments:      strings.TrimSpace(entry.Comments),
+			HostEntered:   true,
+		}
+		if err := ValidateScores(rating); err != nil {
+			return nil, fmt.Errorf("[controllers.rating.ImportRatings] rating %d is invalid: %w", i+1, err)
+		}
+		ratings = append(ratings, rating)
+		usernames = append(usernames, username)
+	}
+
+	result := &ImportResult{
+		Imported:            len(ratings),
+		CreatedParticipants: make([]string, 0),
+	}
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		participantIDs := make(map[string]string)
+		for i, rating := range ratings {
+			username := usernames[i]
+			if _, ok := participantIDs[username]; !ok {
+				participant, created, err := c.participantController.GetOrCreateParticipantTx(ctx, tx, gameID, username)
+				if err != nil {
+					return fmt.Errorf("[controllers.rating.ImportRatings] failed to get participant: %w", err)
+				}
+				participantIDs[username] = participant.ParticipantID
+				if created {
+					result.CreatedParticipants = append(result.CreatedParticipants, username)
+				}
+			}
+			rating.ParticipantID = participantIDs[username]
+			if err := upsertHostRatingTx(ctx, tx, rating); err != nil {
+				return fmt.Errorf("[controllers.rating.ImportRatings] failed to save rating: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[controllers.rating.ImportRatings] failed to import ratings: %w", err)
+	}
+	return result, nil
+}
+
+func upsertHostRatingTx(ctx context.Context, tx *sqlx.Tx, rating *Rating) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rating (
+			rating_id,
+			game_id,
+			participant_id,
+			wine_id,
+			sight_rating,
+			aroma_rating,
+			taste_rating,
+			overall_rating,
+			comments,
+			host_entered
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			TRUE
+		)
+		ON CONFLICT (participant_id, wine_id) DO UPDATE SET
+			sight_rating = excluded.sight_rating,
+			aroma_rating = excluded.aroma_rating,
+			taste_rating = excluded.taste_rating,
+			overall_rating = excluded.overall_rating,
+			comments = excluded.comments,
+			host_entered = TRUE,
+			updated_at = NOW()
+		;
+	`, uuid.New().String(), rating.GameID, rating.ParticipantID, rating.WineID, rating.SightRating, rating.AromaRating, rating.TasteRating, rating.OverallRating, rating.Comments); err != nil {
+		return fmt.Errorf("[rating.upsertHostRatingTx] failed to upsert rating: %w", err)
+	}
+	return nil
+}
+
+// ParseRatingsCSV reads host entered ratings from a CSV file with a header row. The columns match the ratings export,
+// so an exported file can be edited and uploaded again. Rows without a username are given the default username.
+func ParseRatingsCSV(r io.Reader, defaultUsername string) ([]*ProxyRating, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("[rating.ParseRatingsCSV] file is empty: %w", werrors.ErrBadRequest)
+		}
+		return nil, fmt.Errorf("[rating.ParseRatingsCSV] could not read header: %v: %w", err, werrors.ErrBadRequest)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		if column, ok := csvColumns[normalizeColumn(name)]; ok {
+			columns[column] = i
+		}
+	}
+	if _, ok := columns["wineCode"]; !ok {
+		return nil, fmt.Errorf("[rating.ParseRatingsCSV] missing wine code column: %w", werrors.ErrBadRequest)
+	}
+	if _, ok := columns["username"]; !ok && defaultUsername == "" {
+		return nil, fmt.Errorf("[rating.ParseRatingsCSV] missing username column: %w", werrors.ErrBadRequest)
+	}
+	entries := make([]*ProxyRating, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("[rating.ParseRatingsCSV] could not read row: %v: %w", err, werrors.ErrBadRequest)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		entry := &ProxyRating{
+			Username: field("username"),
+			WineCode: field("wineCode"),
+			Comments: field("comments"),
+		}
+		if entry.Username == "" {
+			entry.Username = defaultUsername
+		}
+		scores := map[string]*float64{
+			CategorySight:   &entry.SightRating,
+			CategoryAroma:   &entry.AromaRating,
+			CategoryTaste:   &entry.TasteRating,
+			CategoryOverall: &entry.OverallRating,
+		}
+		for key, score := range scores {
+			value := field(key)
+			if value == "" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+				return nil, fmt.Errorf("[rating.ParseRatingsCSV] line %d has invalid %s score %q: %w", line, key, value, werrors.ErrBadRequest)
+			}
+			*score = parsed
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// csvColumns maps normalized header names to the fields they fill in
+var csvColumns = map[string]string{
+	"username":      "username",
+	"participant":   "username",
+	"winecode":      "wineCode",
+	"code":          "wineCode",
+	"sight":         CategorySight,
+	"sightrating":   CategorySight,
+	"aroma":         CategoryAroma,
+	"aromarating":   CategoryAroma,
+	"taste":         CategoryTaste,
+	"tasterating":   CategoryTaste,
+	"overall":       CategoryOverall,
+	"overallrating": CategoryOverall,
+	"comments":      "comments",
+	"comment":       "comments",
+}
+
+func normalizeColumn(name string) string {
+	// Spreadsheet programs often start CSV files with a byte order mark
+	name = strings.TrimPrefix(name, "\ufeff")
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
@@ -7,10 +7,13 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
 	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
 )
 
 func (c *Controller) GetAllByGameID(ctx context.Context, gameID string) ([]*Rating, error) {
+	ctx, span := tracing.Start(ctx, "rating.GetAllByGameID")
+	defer span.End()
 	rows, err := c.db.DB.QueryxContext(ctx, `
 		SELECT
 			rating_id,
@@ -23,12 +26,14 @@ func (c *Controller) GetAllByGameID(ctx context.Context, gameID string) ([]*Rati
 			taste_rating,
 			overall_rating,
 			(sight_rating + aroma_rating + taste_rating + overall_rating) AS total_rating,
-			comments
+			comments,
+			host_entered
 		FROM
 			rating r
 			INNER JOIN participant p ON r.participant_id = p.participant_id
 		WHERE
 			r.game_id = $1
+			AND r.deleted_at IS NULL
 		;
 	`, gameID)
 	if err != nil {
@@ -53,6 +58,7 @@ func (c *Controller) GetAllByGameID(ctx context.Context, gameID string) ([]*Rati
 			&rating.OverallRating,
 			&rating.TotalRating,
 			&rating.Comments,
+			&rating.HostEntered,
 		); err != nil {
 			return nil, fmt.Errorf("[rating.GetAllByGameID] failed to scan row: %w", err)
 		}
@@ -62,6 +68,8 @@ func (c *Controller) GetAllByGameID(ctx context.Context, gameID string) ([]*Rati
 }
 
 func (c *Controller) GetAllByGameIDAndParticipantID(ctx context.Context, gameID, participantID string) ([]*Rating, error) {
+	ctx, span := tracing.Start(ctx, "rating.GetAllByGameIDAndParticipantID")
+	defer span.End()
 	rows, err := c.db.DB.QueryxContext(ctx, `
 		SELECT
 			rating_id,
@@ -73,12 +81,14 @@ func (c *Controller) GetAllByGameIDAndParticipantID(ctx context.Context, gameID,
 			taste_rating,
 			overall_rating,
 			(sight_rating + aroma_rating + taste_rating + overall_rating) AS total_rating,
-			comments
+			comments,
+			host_entered
 		FROM
 			rating
 		WHERE
 			game_id = $1
 			AND participant_id = $2
+			AND deleted_at IS NULL
 		;
 	`, gameID, participantID)
 	if err != nil {
@@ -102,6 +112,7 @@ func (c *Controller) GetAllByGameIDAndParticipantID(ctx context.Context, gameID,
 			&rating.OverallRating,
 			&rating.TotalRating,
 			&rating.Comments,
+			&rating.HostEntered,
 		); err != nil {
 			return nil, fmt.Errorf("[rating.GetAllByGameIDAndParticipantID] failed to scan row: %w", err)
 		}
@@ -111,6 +122,18 @@ func (c *Controller) GetAllByGameIDAndParticipantID(ctx context.Context, gameID,
 }
 
 func (c *Controller) UpsertRating(ctx context.Context, rating *Rating) (*Rating, error) {
+	ctx, span := tracing.Start(ctx, "rating.UpsertRating")
+	defer span.End()
+	if err := ValidateScores(rating); err != nil {
+		return nil, fmt.Errorf("[rating.UpsertRating] invalid rating: %w", err)
+	}
+	if _, err := c.gameController.GetSingle(ctx, rating.GameID); err != nil {
+		return nil, fmt.Errorf("[rating.UpsertRating] failed to get game: %w", err)
+	}
+	// A deleted wine keeps its ratings in case it is restored, so it cannot be rated in the meantime
+	if _, err := c.wineController.GetSingleWine(ctx, rating.WineID); err != nil {
+		return nil, fmt.Errorf("[rating.UpsertRating] failed to get wine: %w", err)
+	}
 	existingRating, err := c.GetRatingByParticipantIDAndWineID(ctx, rating.ParticipantID, rating.WineID)
 	if err != nil {
 		if errors.Is(err, werrors.ErrNotFound) {
@@ -126,6 +149,8 @@ func (c *Controller) UpsertRating(ctx context.Context, rating *Rating) (*Rating,
 }
 
 func (c *Controller) CreateRating(ctx context.Context, rating *Rating) (*Rating, error) {
+	ctx, span := tracing.Start(ctx, "rating.CreateRating")
+	defer span.End()
 	ratingID := uuid.New().String()
 	if _, err := c.db.DB.ExecContext(ctx, `
 		INSERT INTO rating (
@@ -137,7 +162,8 @@ func (c *Controller) CreateRating(ctx context.Context, rating *Rating) (*Rating,
 			aroma_rating,
 			taste_rating,
 			overall_rating,
-			comments
+			comments,
+			host_entered
 		) VALUES (
 			$1,
 			$2,
@@ -147,10 +173,11 @@ func (c *Controller) CreateRating(ctx context.Context, rating *Rating) (*Rating,
 			$6,
 			$7,
 			$8,
-			$9
+			$9,
+			$10
 		)
 		;
-	`, ratingID, rating.GameID, rating.ParticipantID, rating.WineID, rating.SightRating, rating.AromaRating, rating.TasteRating, rating.OverallRating, rating.Comments); err != nil {
+	`, ratingID, rating.GameID, rating.ParticipantID, rating.WineID, rating.SightRating, rating.AromaRating, rating.TasteRating, rating.OverallRating, rating.Comments, rating.HostEntered); err != nil {
 		return nil, fmt.Errorf("[rating.CreateRating] failed to create rating: %w", err)
 	}
 	createdRating, err := c.GetRatingByParticipantIDAndWineID(ctx, rating.ParticipantID, rating.WineID)
@@ -161,6 +188,8 @@ func (c *Controller) CreateRating(ctx context.Context, rating *Rating) (*Rating,
 }
 
 func (c *Controller) UpdateRating(ctx context.Context, ratingID string, rating *Rating) (*Rating, error) {
+	ctx, span := tracing.Start(ctx, "rating.UpdateRating")
+	defer span.End()
 	if _, err := c.db.DB.ExecContext(ctx, `
 		UPDATE
 			rating
@@ -169,11 +198,13 @@ func (c *Controller) UpdateRating(ctx context.Context, ratingID string, rating *
 			aroma_rating = $2,
 			taste_rating = $3,
 			overall_rating = $4,
-			comments = $5
+			comments = $5,
+			host_entered = $6,
+			updated_at = NOW()
 		WHERE
-			rating_id = $6
+			rating_id = $7
 		;
-	`, rating.SightRating, rating.AromaRating, rating.TasteRating, rating.OverallRating, rating.Comments, ratingID); err != nil {
+	`, rating.SightRating, rating.AromaRating, rating.TasteRating, rating.OverallRating, rating.Comments, rating.HostEntered, ratingID); err != nil {
 		return nil, fmt.Errorf("[rating.UpdateRating] failed to update rating: %w", err)
 	}
 	updatedRating, err := c.GetRatingByParticipantIDAndWineID(ctx, rating.ParticipantID, rating.WineID)
@@ -184,6 +215,8 @@ func (c *Controller) UpdateRating(ctx context.Context, ratingID string, rating *
 }
 
 func (c *Controller) GetRatingByParticipantIDAndWineID(ctx context.Context, participantID, wineID string) (*Rating, error) {
+	ctx, span := tracing.Start(ctx, "rating.GetRatingByParticipantIDAndWineID")
+	defer span.End()
 	row := c.db.DB.QueryRowxContext(ctx, `
 		SELECT
 			rating_id,
@@ -194,12 +227,14 @@ func (c *Controller) GetRatingByParticipantIDAndWineID(ctx context.Context, part
 			aroma_rating,
 			taste_rating,
 			overall_rating,
-			comments
+			comments,
+			host_entered
 		FROM
 			rating
 		WHERE
 			participant_id = $1
 			AND wine_id = $2
+			AND deleted_at IS NULL
 		;
 	`, participantID, wineID)
 	var rating Rating
@@ -213,6 +248,7 @@ func (c *Controller) GetRatingByParticipantIDAndWineID(ctx context.Context, part
 		&rating.TasteRating,
 		&rating.OverallRating,
 		&rating.Comments,
+		&rating.HostEntered,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, werrors.ErrNotFound
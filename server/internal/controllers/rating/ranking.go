@@ -0,0 +1,220 @@
+package rating
+
+import (
+	"math"
+	"sort"
+)
+
+type RankingStrategy string
+
+const (
+	StrategyMean        RankingStrategy = "mean"
+	StrategyMedian      RankingStrategy = "median"
+	StrategyTrimmedMean RankingStrategy = "trimmedMean"
+	StrategyOlympic     RankingStrategy = "olympic"
+	StrategyBayesian    RankingStrategy = "bayesian"
+)
+
+type TieBreaker string
+
+const (
+	TieBreakerRatingCount TieBreaker = "ratingCount"
+	TieBreakerTaste       TieBreaker = "taste"
+	TieBreakerShared      TieBreaker = "shared"
+)
+
+// trimFraction is the fraction of scores dropped from each end for the trimmed mean
+const trimFraction = 0.2
+
+// scoreTolerance absorbs floating point error so wines given the same scores still tie.
+// Scores are compared unrounded and only rounded for display.
+const scoreTolerance = 1e-9
+
+func IsValidStrategy(strategy string) bool {
+	switch RankingStrategy(strategy) {
+	case StrategyMean, StrategyMedian, StrategyTrimmedMean, StrategyOlympic, StrategyBayesian:
+		return true
+	}
+	return false
+}
+
+func IsValidTieBreaker(tieBreaker string) bool {
+	switch TieBreaker(tieBreaker) {
+	case TieBreakerRatingCount, TieBreakerTaste, TieBreakerShared:
+		return true
+	}
+	return false
+}
+
+// wineScores holds the non-empty scores given to a single wine
+type wineScores struct {
+	totals     []float64
+	categories map[string][]float64
+	score      float64
+}
+
+func newWineScores() *wineScores {
+	ws := &wineScores{
+		totals:     make([]float64, 0),
+		categories: make(map[string][]float64, len(Categories)),
+	}
+	for _, category := range Categories {
+		ws.categories[category.Key] = make([]float64, 0)
+	}
+	return ws
+}
+
+func (ws *wineScores) add(rating *Rating) {
+	ws.totals = append(ws.totals, rating.TotalRating)
+	for _, category := range Categories {
+		ws.categories[category.Key] = append(ws.categories[category.Key], rating.categoryScore(category.Key))
+	}
+}
+
+// applyStrategy sets the score of every wine using the given strategy
+func applyStrategy(strategy RankingStrategy, wines []*wineScores) {
+	// The Bayesian average pulls each wine towards the mean of every score in the game,
+	// weighted by the average number of ratings per wine
+	allTotals := make([]float64, 0)
+	for _, ws := range wines {
+		allTotals = append(allTotals, ws.totals...)
+	}
+	priorMean := mean(allTotals)
+	priorWeight := 0.0
+	if len(wines) > 0 {
+		priorWeight = float64(len(allTotals)) / float64(len(wines))
+	}
+	for _, ws := range wines {
+		switch strategy {
+		case StrategyMedian:
+			ws.score = median(ws.totals)
+		case StrategyTrimmedMean:
+			trim := int(math.Floor(float64(len(ws.totals)) * trimFraction))
+			ws.score = mean(trimmed(ws.totals, trim))
+		case StrategyOlympic:
+			if len(ws.totals) >= 3 {
+				ws.score = mean(trimmed(ws.totals, 1))
+			} else {
+				ws.score = mean(ws.totals)
+			}
+		case StrategyBayesian:
+			sum := 0.0
+			for _, total := range ws.totals {
+				sum += total
+			}
+			if priorWeight+float64(len(ws.totals)) == 0 {
+				ws.score = 0
+			} else {
+				ws.score = (priorWeight*priorMean + sum) / (priorWeight + float64(len(ws.totals)))
+			}
+		default:
+			ws.score = mean(ws.totals)
+		}
+	}
+}
+
+// rankResults sorts the results from best to worst and sets the displayed score and rank of each row
+func rankResults(results []*wineResult, tieBreakers []TieBreaker) []map[string]any {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if diff := compareScores(a.scores.score, b.scores.score); diff != 0 {
+			return diff > 0
+		}
+		if diff := compareTie(tieBreakers, a.scores, b.scores); diff != 0 {
+			return diff > 0
+		}
+		return a.wine.WineCode < b.wine.WineCode
+	})
+	sharedRanks := hasTieBreaker(tieBreakers, TieBreakerShared)
+	rows := make([]map[string]any, len(results))
+	ranks := make([]int, len(results))
+	for i, result := range results {
+		ranks[i] = i + 1
+		if sharedRanks && i > 0 {
+			prev := results[i-1]
+			if compareScores(prev.scores.score, result.scores.score) == 0 && compareTie(tieBreakers, prev.scores, result.scores) == 0 {
+				ranks[i] = ranks[i-1]
+			}
+		}
+		result.row["score"] = roundScore(result.scores.score)
+		result.row["rank"] = ranks[i]
+		rows[i] = result.row
+	}
+	return rows
+}
+
+// compareScores returns a positive number if a is higher than b, negative if lower and 0 if they are equal
+func compareScores(a, b float64) float64 {
+	if math.Abs(a-b) < scoreTolerance {
+		return 0
+	}
+	return a - b
+}
+
+// compareTie returns a positive number if a should be ranked above b, negative if below and 0 if still tied
+func compareTie(tieBreakers []TieBreaker, a, b *wineScores) float64 {
+	for _, tieBreaker := range tieBreakers {
+		var diff float64
+		switch tieBreaker {
+		case TieBreakerRatingCount:
+			diff = float64(len(a.totals) - len(b.totals))
+		case TieBreakerTaste:
+			diff = compareScores(a.categoryMean(CategoryTaste), b.categoryMean(CategoryTaste))
+		}
+		if diff != 0 {
+			return diff
+		}
+	}
+	return 0
+}
+
+func hasTieBreaker(tieBreakers []TieBreaker, tieBreaker TieBreaker) bool {
+	for _, t := range tieBreakers {
+		if t == tieBreaker {
+			return true
+		}
+	}
+	return false
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := sortedCopy(values)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// trimmed returns the values with n values dropped from each end
+func trimmed(values []float64, n int) []float64 {
+	if n <= 0 || len(values) <= 2*n {
+		return values
+	}
+	return sortedCopy(values)[n : len(values)-n]
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
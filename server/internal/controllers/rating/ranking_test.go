@@ -0,0 +1,191 @@
+package rating
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
+)
+
+// newRankingResult builds a result row with one rating for each taste score
+func newRankingResult(code string, score float64, tastes ...float64) *wineResult {
+	result := &wineResult{
+		wine:       &wine.Wine{WineID: code, WineCode: code},
+		scores:     newWineScores(),
+		byUsername: make(map[string]float64),
+		row:        make(map[string]any),
+	}
+	for _, taste := range tastes {
+		result.scores.add(&Rating{TasteRating: taste, TotalRating: score})
+	}
+	result.scores.score = score
+	return result
+}
+
+func newScores(totals ...float64) *wineScores {
+	ws := newWineScores()
+	for _, total := range totals {
+		ws.add(&Rating{TotalRating: total})
+	}
+	return ws
+}
+
+func TestApplyStrategy(t *testing.T) {
+	// The Bayesian prior is the mean of all 9 scores, 255/9, weighted by the 3 ratings per wine
+	tests := []struct {
+		strategy RankingStrategy
+		want     []float64
+	}{
+		{strategy: StrategyMean, want: []float64{40, 50, 5.0 / 3}},
+		{strategy: StrategyMedian, want: []float64{30, 50, 2}},
+		{strategy: StrategyTrimmedMean, want: []float64{30, 50, 5.0 / 3}},
+		{strategy: StrategyOlympic, want: []float64{30, 50, 2}},
+		{strategy: StrategyBayesian, want: []float64{285.0 / 8, 135.0 / 4, 15}},
+		{strategy: "", want: []float64{40, 50, 5.0 / 3}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			wines := []*wineScores{
+				newScores(10, 20, 30, 40, 100),
+				newScores(50),
+				newScores(1, 2, 2),
+			}
+			applyStrategy(tt.strategy, wines)
+			got := make([]float64, len(wines))
+			for i, ws := range wines {
+				got[i] = ws.score
+			}
+			assertAllClose(t, "scores", got, tt.want)
+		})
+	}
+}
+
+func TestApplyStrategyWithoutRatings(t *testing.T) {
+	wines := []*wineScores{newScores()}
+	applyStrategy(StrategyBayesian, wines)
+	if wines[0].score != 0 {
+		t.Fatalf("score was %v, want 0", wines[0].score)
+	}
+}
+
+func TestCompareTie(t *testing.T) {
+	tests := []struct {
+		name        string
+		tieBreakers []TieBreaker
+		a, b        *wineResult
+		want        float64
+	}{
+		{name: "no tie breakers", a: newRankingResult("A", 8, 5, 5, 5), b: newRankingResult("B", 8, 1, 1), want: 0},
+		{name: "rating count", tieBreakers: []TieBreaker{TieBreakerRatingCount}, a: newRankingResult("A", 8, 1, 1, 1), b: newRankingResult("B", 8, 5, 5), want: 1},
+		{name: "taste", tieBreakers: []TieBreaker{TieBreakerTaste}, a: newRankingResult("A", 8, 1, 1, 1), b: newRankingResult("B", 8, 5, 5), want: -1},
+		// Both taste means round to 4.00
+		{name: "taste compared unrounded", tieBreakers: []TieBreaker{TieBreakerTaste}, a: newRankingResult("A", 8, 4.001, 4.003), b: newRankingResult("B", 8, 4, 4.002), want: 1},
+		{name: "equal taste", tieBreakers: []TieBreaker{TieBreakerTaste}, a: newRankingResult("A", 8, 4, 5), b: newRankingResult("B", 8, 5, 4), want: 0},
+		{name: "rating count then taste", tieBreakers: []TieBreaker{TieBreakerRatingCount, TieBreakerTaste}, a: newRankingResult("A", 8, 3, 4), b: newRankingResult("B", 8, 5, 4), want: -1},
+		{name: "rating count decides before taste", tieBreakers: []TieBreaker{TieBreakerRatingCount, TieBreakerTaste}, a: newRankingResult("A", 8, 1, 1, 1), b: newRankingResult("B", 8, 5, 5), want: 1},
+		{name: "taste then rating count", tieBreakers: []TieBreaker{TieBreakerTaste, TieBreakerRatingCount}, a: newRankingResult("A", 8, 4, 4), b: newRankingResult("B", 8, 4, 4, 4), want: -1},
+		{name: "taste decides before rating count", tieBreakers: []TieBreaker{TieBreakerTaste, TieBreakerRatingCount}, a: newRankingResult("A", 8, 5, 5), b: newRankingResult("B", 8, 4, 4, 4), want: 1},
+		{name: "shared does not break ties", tieBreakers: []TieBreaker{TieBreakerShared}, a: newRankingResult("A", 8, 5, 5, 5), b: newRankingResult("B", 8, 1, 1), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareTie(tt.tieBreakers, tt.a.scores, tt.b.scores)
+			if sign(got) != tt.want {
+				t.Fatalf("comparison was %v, want the sign of %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func sign(value float64) float64 {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	}
+	return 0
+}
+
+func TestRankResults(t *testing.T) {
+	tests := []struct {
+		name        string
+		tieBreakers []TieBreaker
+		results     []*wineResult
+		order       []string
+		ranks       []int
+		scores      []float64
+	}{
+		{
+			name:    "highest score first",
+			results: []*wineResult{newRankingResult("A", 7, 4), newRankingResult("B", 9, 4), newRankingResult("C", 8, 4)},
+			order:   []string{"B", "C", "A"},
+			ranks:   []int{1, 2, 3},
+			scores:  []float64{9, 8, 7},
+		},
+		{
+			// Both scores display as 8 but are not tied
+			name:        "scores that round the same",
+			tieBreakers: []TieBreaker{TieBreakerShared},
+			results:     []*wineResult{newRankingResult("A", 8.001, 4), newRankingResult("B", 8.004, 4)},
+			order:       []string{"B", "A"},
+			ranks:       []int{1, 2},
+			scores:      []float64{8, 8},
+		},
+		{
+			name:        "floating point error",
+			tieBreakers: []TieBreaker{TieBreakerShared},
+			results:     []*wineResult{newRankingResult("A", 0.1+0.2, 4), newRankingResult("B", 0.3, 4)},
+			order:       []string{"A", "B"},
+			ranks:       []int{1, 1},
+			scores:      []float64{0.3, 0.3},
+		},
+		{
+			name:    "wine code settles ties",
+			results: []*wineResult{newRankingResult("B", 8, 4), newRankingResult("A", 8, 4), newRankingResult("C", 7, 4)},
+			order:   []string{"A", "B", "C"},
+			ranks:   []int{1, 2, 3},
+			scores:  []float64{8, 8, 7},
+		},
+		{
+			name:        "shared ranks",
+			tieBreakers: []TieBreaker{TieBreakerShared},
+			results:     []*wineResult{newRankingResult("B", 8, 4), newRankingResult("A", 8, 4), newRankingResult("C", 7, 4)},
+			order:       []string{"A", "B", "C"},
+			ranks:       []int{1, 1, 3},
+			scores:      []float64{8, 8, 7},
+		},
+		{
+			name:        "rating count before shared ranks",
+			tieBreakers: []TieBreaker{TieBreakerRatingCount, TieBreakerShared},
+			results:     []*wineResult{newRankingResult("A", 8, 4), newRankingResult("B", 8, 4, 4), newRankingResult("C", 8, 4)},
+			order:       []string{"B", "A", "C"},
+			ranks:       []int{1, 2, 2},
+			scores:      []float64{8, 8, 8},
+		},
+		{
+			name:        "taste then rating count",
+			tieBreakers: []TieBreaker{TieBreakerTaste, TieBreakerRatingCount},
+			results:     []*wineResult{newRankingResult("A", 8, 4, 4), newRankingResult("B", 8, 5), newRankingResult("C", 8, 4, 4, 4)},
+			order:       []string{"B", "C", "A"},
+			ranks:       []int{1, 2, 3},
+			scores:      []float64{8, 8, 8},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := rankResults(tt.results, tt.tieBreakers)
+			for i, row := range rows {
+				if code := tt.results[i].wine.WineCode; code != tt.order[i] {
+					t.Fatalf("position %d was %s, want %s", i+1, code, tt.order[i])
+				}
+				if rank := row["rank"]; rank != tt.ranks[i] {
+					t.Fatalf("%s was ranked %v, want %d", tt.order[i], rank, tt.ranks[i])
+				}
+				if score := row["score"].(float64); math.Abs(score-tt.scores[i]) > tolerance {
+					t.Fatalf("%s displayed a score of %v, want %v", tt.order[i], score, tt.scores[i])
+				}
+			}
+		})
+	}
+}
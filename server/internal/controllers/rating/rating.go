@@ -3,32 +3,50 @@ package rating
 import (
 	"context"
 	"fmt"
-	"math"
-	"sort"
 
 	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
 	"github.com/jacobtie/rating-party/server/internal/controllers/participant"
 	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
 	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
 )
 
 type Controller struct {
 	cfg                   *config.Config
 	db                    *db.DB
+	gameController        *game.Controller
 	participantController *participant.Controller
 	wineController        *wine.Controller
 }
 
-func NewController(cfg *config.Config, db *db.DB, participantController *participant.Controller, wineController *wine.Controller) *Controller {
+func NewController(cfg *config.Config, db *db.DB, gameController *game.Controller, participantController *participant.Controller, wineController *wine.Controller) *Controller {
 	return &Controller{
 		cfg:                   cfg,
 		db:                    db,
+		gameController:        gameController,
 		participantController: participantController,
 		wineController:        wineController,
 	}
 }
 
-func (c *Controller) GetRatingsResult(ctx context.Context, gameID string, includeUsernames bool) ([]map[string]any, error) {
+func (c *Controller) GetRatingsResult(ctx context.Context, gameID string, includeUsernames bool, opts ResultOptions) (*RatingsResult, error) {
+	ctx, span := tracing.Start(ctx, "rating.GetRatingsResult")
+	defer span.End()
+	game, err := c.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.GetRatingsResult] could not get game: %w", err)
+	}
+	strategy := RankingStrategy(game.RankingStrategy)
+	if !IsValidStrategy(game.RankingStrategy) {
+		strategy = StrategyMean
+	}
+	tieBreakers := make([]TieBreaker, 0, len(game.TieBreakers))
+	for _, tieBreaker := range game.TieBreakers {
+		if IsValidTieBreaker(tieBreaker) {
+			tieBreakers = append(tieBreakers, TieBreaker(tieBreaker))
+		}
+	}
 	ratings, err := c.GetAllByGameID(ctx, gameID)
 	if err != nil {
 		return nil, fmt.Errorf("[controllers.rating.GetRatingsResult] could not get all ratings: %w", err)
@@ -37,27 +55,44 @@ func (c *Controller) GetRatingsResult(ctx context.Context, gameID string, includ
 	if err != nil {
 		return nil, fmt.Errorf("[controllers.rating.GetRatingsResult] could not get all participants: %w", err)
 	}
-	usernames := make([]string, len(participants))
+	usernames := make([]string, 0, len(participants))
 	for _, participant := range participants {
 		usernames = append(usernames, participant.Username)
 	}
-	wineAggMap := make(map[string]map[string]float64)
+	// Duplicate pours are folded into their original wine when the game merges duplicates
+	duplicateOf := make(map[string]string)
+	if game.MergeDuplicates {
+		wines, err := c.wineController.GetAllWines(ctx, gameID)
+		if err != nil {
+			return nil, fmt.Errorf("[controllers.rating.GetRatingsResult] could not get all wines: %w", err)
+		}
+		for _, wine := range wines {
+			if wine.DuplicateOfWineID != "" {
+				duplicateOf[wine.WineID] = wine.DuplicateOfWineID
+			}
+		}
+	}
+	wineAggMap := make(map[string]map[string]*Rating)
 	for _, rating := range ratings {
 		// Skip empty ratings
-		if rating.SightRating == 0 &&
-			rating.AromaRating == 0 &&
-			rating.TasteRating == 0 &&
-			rating.OverallRating == 0 &&
-			rating.Comments == "" {
+		if rating.isEmpty() {
 			continue
 		}
-		if _, ok := wineAggMap[rating.WineID]; !ok {
-			wineAggMap[rating.WineID] = make(map[string]float64)
+		wineID := rating.WineID
+		if originalWineID, ok := duplicateOf[wineID]; ok {
+			wineID = originalWineID
+		}
+		if _, ok := wineAggMap[wineID]; !ok {
+			wineAggMap[wineID] = make(map[string]*Rating)
 		}
-		wineAggMap[rating.WineID][rating.Username] = rating.SightRating + rating.AromaRating + rating.TasteRating + rating.OverallRating
+		if existing, ok := wineAggMap[wineID][rating.Username]; ok {
+			wineAggMap[wineID][rating.Username] = mergeRatings(existing, rating)
+			continue
+		}
+		wineAggMap[wineID][rating.Username] = rating
 	}
-	rows := make([]map[string]any, 0)
-	for wineID, wineScores := range wineAggMap {
+	results := make([]*wineResult, 0)
+	for wineID, wineRatings := range wineAggMap {
 		wine, err := c.wineController.GetSingleWine(ctx, wineID)
 		// Wine may have been deleted, do not include in results
 		if err != nil {
@@ -68,34 +103,78 @@ func (c *Controller) GetRatingsResult(ctx context.Context, gameID string, includ
 		row["wineName"] = wine.WineName
 		row["wineCode"] = wine.WineCode
 		row["wineYear"] = wine.WineYear
-		sum := 0.0
-		count := 0
+		scores := newWineScores()
+		byUsername := make(map[string]float64)
 		for _, username := range usernames {
-			score, ok := wineScores[username]
-			if !ok {
-				score = 0.0
-			}
-			if includeUsernames {
-				row[username] = score
-			}
+			rating, ok := wineRatings[username]
 			if !ok {
 				continue
 			}
-			sum += score
-			count += 1
+			scores.add(rating)
+			byUsername[username] = rating.TotalRating
 		}
-		if count == 0 {
-			row["avg"] = 0.0
-		} else {
-			row["avg"] = math.Round((sum/float64(count))*100) / 100
+		// Participant scores get their own key so a username can never clash with the other columns
+		if includeUsernames {
+			participantScores := make(map[string]float64, len(usernames))
+			for _, username := range usernames {
+				participantScores[username] = byUsername[username]
+			}
+			row["scores"] = participantScores
 		}
-		rows = append(rows, row)
+		row["avg"] = roundScore(mean(scores.totals))
+		row["ratingCount"] = len(scores.totals)
+		row["stdDev"] = roundScore(standardDeviation(scores.totals))
+		row["histogram"] = histogram(scores.totals)
+		results = append(results, &wineResult{
+			wine:       wine,
+			scores:     scores,
+			byUsername: byUsername,
+			row:        row,
+		})
+	}
+	allScores := make([]*wineScores, len(results))
+	for i, result := range results {
+		allScores[i] = result.scores
 	}
-	sort.Slice(rows, func(i, j int) bool {
-		return rows[i]["avg"].(float64) > rows[j]["avg"].(float64)
-	})
-	for i := 0; i < len(rows); i++ {
-		rows[i]["rank"] = i + 1
+	applyStrategy(strategy, allScores)
+	rows := rankResults(results, tieBreakers)
+	tieBreakerNames := make([]string, len(tieBreakers))
+	for i, tieBreaker := range tieBreakers {
+		tieBreakerNames[i] = string(tieBreaker)
+	}
+	ratingsResult := &RatingsResult{
+		RankingStrategy: string(strategy),
+		TieBreakers:     tieBreakerNames,
+		MergeDuplicates: game.MergeDuplicates,
+		Rows:            rows,
+		Categories:      buildCategoryLeaderboards(results),
+	}
+	if opts.IncludeStatistics {
+		method := opts.PostHocMethod
+		if method == "" {
+			method = PostHocNemenyi
+		}
+		ratingsResult.Statistics = buildStatistics(results, method)
 	}
-	return rows, nil
+	return ratingsResult, nil
+}
+
+// wineResult is an intermediate result row used while ranking wines
+type wineResult struct {
+	wine       *wine.Wine
+	scores     *wineScores
+	byUsername map[string]float64
+	row        map[string]any
+}
+
+// rowWineID returns the wine ID of a result row
+func rowWineID(row map[string]any) string {
+	wineID, _ := row["wineID"].(string)
+	return wineID
+}
+
+// rowScores returns the participant scores of a result row, which is empty when usernames were left out
+func rowScores(row map[string]any) map[string]float64 {
+	scores, _ := row["scores"].(map[string]float64)
+	return scores
 }
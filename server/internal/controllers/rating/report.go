@@ -0,0 +1,215 @@
+package rating
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/platform/pdfdoc"
+	"github.com/jacobtie/rating-party/server/internal/platform/spreadsheet"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+)
+
+// participantColumnsPerTable is how many wines fit next to the username in the participant scores table
+const participantColumnsPerTable = 7
+
+// ResultsReport renders a printable PDF of the results and returns it with its filename.
+// Participants may only download it once results are shared, and never see participant scores or who wrote each comment.
+func (c *Controller) ResultsReport(ctx context.Context, gameID string, isAdmin bool) ([]byte, string, error) {
+	ctx, span := tracing.Start(ctx, "rating.ResultsReport")
+	defer span.End()
+	game, err := c.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, "", fmt.Errorf("[controllers.rating.ResultsReport] could not get game: %w", err)
+	}
+	if !isAdmin && !game.AreResultsShared {
+		return nil, "", fmt.Errorf("[controllers.rating.ResultsReport] results are not shared: %w", werrors.ErrForbidden)
+	}
+	result, err := c.GetRatingsResult(ctx, gameID, isAdmin, ResultOptions{IncludeStatistics: true})
+	if err != nil {
+		return nil, "", fmt.Errorf("[controllers.rating.ResultsReport] could not get ratings result: %w", err)
+	}
+	ratings, err := c.GetAllByGameID(ctx, gameID)
+	if err != nil {
+		return nil, "", fmt.Errorf("[controllers.rating.ResultsReport] could not get all ratings: %w", err)
+	}
+	// Comments on duplicate pours are listed under the original wine when duplicates are merged
+	duplicateOf := make(map[string]string)
+	if result.MergeDuplicates {
+		wines, err := c.wineController.GetAllWines(ctx, gameID)
+		if err != nil {
+			return nil, "", fmt.Errorf("[controllers.rating.ResultsReport] could not get all wines: %w", err)
+		}
+		for _, wine := range wines {
+			if wine.DuplicateOfWineID != "" {
+				duplicateOf[wine.WineID] = wine.DuplicateOfWineID
+			}
+		}
+	}
+	ratingsByWine := make(map[string][]*Rating)
+	allTotals := make([]float64, 0)
+	raters := make(map[string]bool)
+	for _, rating := range ratings {
+		if rating.isEmpty() {
+			continue
+		}
+		wineID := rating.WineID
+		if originalWineID, ok := duplicateOf[wineID]; ok {
+			wineID = originalWineID
+		}
+		ratingsByWine[wineID] = append(ratingsByWine[wineID], rating)
+		allTotals = append(allTotals, rating.TotalRating)
+		raters[rating.Username] = true
+	}
+
+	doc := pdfdoc.New(fmt.Sprintf("%s results", game.GameName))
+	doc.Title(game.GameName)
+	doc.Subtitle(fmt.Sprintf("Results generated %s", time.Now().Format("January 2, 2006")))
+
+	doc.Heading("Summary")
+	summary := [][2]string{
+		{"Wines", strconv.Itoa(len(result.Rows))},
+		{"Participants", strconv.Itoa(len(raters))},
+		{"Ratings", strconv.Itoa(len(allTotals))},
+		{"Average score", fmt.Sprintf("%s / %d", formatScore(roundScore(mean(allTotals))), MaxTotal)},
+		{"Score standard deviation", formatScore(roundScore(standardDeviation(allTotals)))},
+		{"Ranking", result.RankingStrategy},
+	}
+	if result.Statistics != nil && result.Statistics.Friedman != nil {
+		friedman := result.Statistics.Friedman
+		verdict := "no significant difference between wines"
+		if friedman.Significant {
+			verdict = "wines differ significantly"
+		}
+		summary = append(summary, [2]string{"Friedman test", fmt.Sprintf("chi-square %s, p = %s (%s)", formatScore(friedman.Statistic), formatScore(friedman.PValue), verdict)})
+	}
+	doc.KeyValues(summary)
+
+	doc.Heading("Ranking")
+	rankingRows := make([][]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		rankingRows = append(rankingRows, []string{
+			fmt.Sprint(row["rank"]),
+			fmt.Sprint(row["wineCode"]),
+			fmt.Sprint(row["wineName"]),
+			fmt.Sprint(row["wineYear"]),
+			formatScore(rowFloat(row, "score")),
+			formatScore(rowFloat(row, "avg")),
+			formatScore(rowFloat(row, "stdDev")),
+			fmt.Sprint(row["ratingCount"]),
+		})
+	}
+	doc.Table(
+		[]string{"Rank", "Code", "Wine", "Year", "Score", "Average", "Std Dev", "Ratings"},
+		[]float64{14, 18, 58, 16, 18, 20, 18, 18},
+		rankingRows,
+	)
+
+	doc.Heading("Categories")
+	categoryHeader := []string{"Code", "Wine"}
+	categoryWidths := []float64{20, 60}
+	categoryAvgs := make(map[string]map[string]float64)
+	winners := make([][2]string, 0, len(result.Categories))
+	for _, leaderboard := range result.Categories {
+		categoryHeader = append(categoryHeader, fmt.Sprintf("%s (%s)", leaderboard.Label, formatScore(leaderboard.Max)))
+		categoryWidths = append(categoryWidths, 25)
+		categoryAvgs[leaderboard.Key] = make(map[string]float64)
+		for _, score := range leaderboard.Wines {
+			categoryAvgs[leaderboard.Key][score.WineID] = score.Avg
+		}
+		if len(leaderboard.Wines) > 0 {
+			best := leaderboard.Wines[0]
+			winners = append(winners, [2]string{
+				fmt.Sprintf("Best %s", leaderboard.Label),
+				fmt.Sprintf("%s %s (%s)", best.WineCode, best.WineName, formatScore(best.Avg)),
+			})
+		}
+	}
+	categoryRows := make([][]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		wineID := rowWineID(row)
+		values := []string{fmt.Sprint(row["wineCode"]), fmt.Sprint(row["wineName"])}
+		for _, category := range Categories {
+			values = append(values, formatScore(categoryAvgs[category.Key][wineID]))
+		}
+		categoryRows = append(categoryRows, values)
+	}
+	doc.KeyValues(winners)
+	doc.Table(categoryHeader, categoryWidths, categoryRows)
+
+	if isAdmin {
+		doc.Heading("Participant scores")
+		usernames := make([]string, 0, len(raters))
+		participants, err := c.participantController.GetAllParticipantsByGameID(ctx, gameID)
+		if err != nil {
+			return nil, "", fmt.Errorf("[controllers.rating.ResultsReport] could not get all participants: %w", err)
+		}
+		for _, participant := range participants {
+			if raters[participant.Username] {
+				usernames = append(usernames, participant.Username)
+			}
+		}
+		// Wide games are split into several tables so every column stays readable
+		for start := 0; start < len(result.Rows); start += participantColumnsPerTable {
+			end := start + participantColumnsPerTable
+			if end > len(result.Rows) {
+				end = len(result.Rows)
+			}
+			header := []string{"Participant"}
+			widths := []float64{40}
+			for _, row := range result.Rows[start:end] {
+				header = append(header, fmt.Sprint(row["wineCode"]))
+				widths = append(widths, 20)
+			}
+			rows := make([][]string, 0, len(usernames))
+			for _, username := range usernames {
+				values := []string{username}
+				for _, row := range result.Rows[start:end] {
+					values = append(values, formatScore(rowScores(row)[username]))
+				}
+				rows = append(rows, values)
+			}
+			doc.Table(header, widths, rows)
+		}
+	}
+
+	doc.Heading("Comments")
+	for _, row := range result.Rows {
+		comments := make([]string, 0)
+		for _, rating := range ratingsByWine[rowWineID(row)] {
+			if rating.Comments == "" {
+				continue
+			}
+			if isAdmin {
+				comments = append(comments, fmt.Sprintf("%s: %s", rating.Username, rating.Comments))
+			} else {
+				comments = append(comments, fmt.Sprintf("- %s", rating.Comments))
+			}
+		}
+		if len(comments) == 0 {
+			continue
+		}
+		doc.Paragraph(fmt.Sprintf("%s  %s (%v)", row["wineCode"], row["wineName"], row["wineYear"]))
+		for _, comment := range comments {
+			doc.Paragraph(comment)
+		}
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		return nil, "", fmt.Errorf("[controllers.rating.ResultsReport] could not render report: %w", err)
+	}
+	return data, spreadsheet.Filename(game.GameName, "results", "pdf"), nil
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// rowFloat returns a score column of a result row
+func rowFloat(row map[string]any, key string) float64 {
+	value, _ := row[key].(float64)
+	return value
+}
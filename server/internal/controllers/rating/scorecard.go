@@ -0,0 +1,57 @@
+package rating
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+)
+
+type Category struct {
+	Key   string  `json:"key"`
+	Label string  `json:"label"`
+	Max   float64 `json:"max"`
+}
+
+const (
+	CategorySight   = "sight"
+	CategoryAroma   = "aroma"
+	CategoryTaste   = "taste"
+	CategoryOverall = "overall"
+)
+
+// Categories is the scorecard every wine is rated on, in the order they are shown to participants
+var Categories = []Category{
+	{Key: CategorySight, Label: "Sight", Max: 4},
+	{Key: CategoryAroma, Label: "Aroma", Max: 6},
+	{Key: CategoryTaste, Label: "Taste", Max: 6},
+	{Key: CategoryOverall, Label: "Overall", Max: 4},
+}
+
+// MaxTotal is the highest total score a wine can be given
+const MaxTotal = 20
+
+func (r *Rating) categoryScore(key string) float64 {
+	switch key {
+	case CategorySight:
+		return r.SightRating
+	case CategoryAroma:
+		return r.AromaRating
+	case CategoryTaste:
+		return r.TasteRating
+	case CategoryOverall:
+		return r.OverallRating
+	}
+	return 0
+}
+
+// ValidateScores checks every category score of the rating is within the scorecard
+func ValidateScores(r *Rating) error {
+	for _, category := range Categories {
+		// NaN fails every comparison, so it has to be rejected on its own
+		if score := r.categoryScore(category.Key); math.IsNaN(score) || score < 0 || score > category.Max {
+			return fmt.Errorf("[rating.ValidateScores] %s score %s is outside 0 to %s: %w", category.Label, formatScore(score), formatScore(category.Max), werrors.ErrBadRequest)
+		}
+	}
+	return nil
+}
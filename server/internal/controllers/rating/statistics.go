@@ -0,0 +1,375 @@
+package rating
+
+import (
+	"math"
+	"sort"
+)
+
+type PostHocMethod string
+
+const (
+	PostHocNemenyi  PostHocMethod = "nemenyi"
+	PostHocWilcoxon PostHocMethod = "wilcoxon"
+)
+
+// significanceLevel is the alpha used for all significance tests
+const significanceLevel = 0.05
+
+func IsValidPostHocMethod(method string) bool {
+	switch PostHocMethod(method) {
+	case PostHocNemenyi, PostHocWilcoxon:
+		return true
+	}
+	return false
+}
+
+// tCritical95 holds the two sided 95% critical values of Student's t distribution for 1 to 30 degrees of freedom
+var tCritical95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// nemenyiQ05 holds the critical values of the Studentized range statistic divided by sqrt(2)
+// at alpha 0.05 for 2 to 20 groups
+var nemenyiQ05 = []float64{
+	1.960, 2.343, 2.569, 2.728, 2.850, 2.949, 3.031, 3.102, 3.164, 3.219,
+	3.268, 3.313, 3.354, 3.391, 3.426, 3.458, 3.489, 3.517, 3.544,
+}
+
+func tCritical(df int) float64 {
+	switch {
+	case df <= 0:
+		return math.NaN()
+	case df <= len(tCritical95):
+		return tCritical95[df-1]
+	case df <= 40:
+		return 2.021
+	case df <= 60:
+		return 2.000
+	case df <= 120:
+		return 1.980
+	}
+	return 1.960
+}
+
+// confidenceInterval returns the 95% confidence interval of the mean of the values
+func confidenceInterval(values []float64) (float64, float64, bool) {
+	if len(values) < 2 {
+		return 0, 0, false
+	}
+	m := mean(values)
+	margin := tCritical(len(values)-1) * standardDeviation(values) / math.Sqrt(float64(len(values)))
+	return m - margin, m + margin, true
+}
+
+// standardDeviation returns the sample standard deviation of the values
+func standardDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sumSquares := 0.0
+	for _, value := range values {
+		sumSquares += (value - m) * (value - m)
+	}
+	return math.Sqrt(sumSquares / float64(len(values)-1))
+}
+
+// averageRanks ranks the values in ascending order starting at 1, giving tied values the average of their ranks
+func averageRanks(values []float64) []float64 {
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return values[indexes[i]] < values[indexes[j]]
+	})
+	ranks := make([]float64, len(values))
+	for i := 0; i < len(indexes); {
+		j := i
+		for j+1 < len(indexes) && values[indexes[j+1]] == values[indexes[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranks[indexes[k]] = rank
+		}
+		i = j + 1
+	}
+	return ranks
+}
+
+// friedman runs the tie corrected Friedman test on blocks of scores, where every block
+// holds one participant's scores for each wine. It returns the statistic, the mean rank of each wine and the p-value.
+func friedman(blocks [][]float64) (float64, []float64, float64) {
+	n := len(blocks)
+	k := len(blocks[0])
+	rankSums := make([]float64, k)
+	sumSquaredRanks := 0.0
+	for _, block := range blocks {
+		for j, rank := range averageRanks(block) {
+			rankSums[j] += rank
+			sumSquaredRanks += rank * rank
+		}
+	}
+	meanRanks := make([]float64, k)
+	expected := float64(n) * float64(k+1) / 2
+	numerator := 0.0
+	for j, rankSum := range rankSums {
+		meanRanks[j] = rankSum / float64(n)
+		numerator += (rankSum - expected) * (rankSum - expected)
+	}
+	denominator := sumSquaredRanks - float64(n*k)*float64(k+1)*float64(k+1)/4
+	if denominator <= 0 {
+		return 0, meanRanks, 1
+	}
+	statistic := float64(k-1) * numerator / denominator
+	return statistic, meanRanks, chiSquareSurvival(statistic, float64(k-1))
+}
+
+// nemenyiCriticalDifference returns the minimum difference in mean ranks for two of k wines
+// rated by n participants to be significantly different
+func nemenyiCriticalDifference(k, n int) (float64, bool) {
+	if k < 2 || k-2 >= len(nemenyiQ05) || n == 0 {
+		return 0, false
+	}
+	return nemenyiQ05[k-2] * math.Sqrt(float64(k*(k+1))/float64(6*n)), true
+}
+
+// wilcoxonSignedRank returns the exact two sided p-value of the Wilcoxon signed rank test on paired samples
+func wilcoxonSignedRank(a, b []float64) float64 {
+	diffs := make([]float64, 0, len(a))
+	for i := range a {
+		if d := a[i] - b[i]; d != 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	if len(diffs) == 0 {
+		return 1
+	}
+	absDiffs := make([]float64, len(diffs))
+	for i, d := range diffs {
+		absDiffs[i] = math.Abs(d)
+	}
+	// Ranks are doubled so tied ranks ending in .5 become integers for the exact distribution
+	ranks := averageRanks(absDiffs)
+	doubledRanks := make([]int, len(ranks))
+	maxSum := 0
+	positiveSum := 0
+	for i, rank := range ranks {
+		doubledRanks[i] = int(math.Round(rank * 2))
+		maxSum += doubledRanks[i]
+		if diffs[i] > 0 {
+			positiveSum += doubledRanks[i]
+		}
+	}
+	// counts[s] is the number of sign assignments whose doubled positive rank sum is s
+	counts := make([]float64, maxSum+1)
+	counts[0] = 1
+	for _, rank := range doubledRanks {
+		for s := maxSum; s >= rank; s-- {
+			counts[s] += counts[s-rank]
+		}
+	}
+	total := math.Pow(2, float64(len(doubledRanks)))
+	lower, upper := 0.0, 0.0
+	for s, count := range counts {
+		if s <= positiveSum {
+			lower += count
+		}
+		if s >= positiveSum {
+			upper += count
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
+
+// holmAdjust applies the Holm-Bonferroni correction to the p-values
+func holmAdjust(pValues []float64) []float64 {
+	m := len(pValues)
+	indexes := make([]int, m)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return pValues[indexes[i]] < pValues[indexes[j]]
+	})
+	adjusted := make([]float64, m)
+	running := 0.0
+	for i, index := range indexes {
+		running = math.Max(running, math.Min(1, float64(m-i)*pValues[index]))
+		adjusted[index] = running
+	}
+	return adjusted
+}
+
+// chiSquareSurvival returns P(X >= x) for a chi-square distribution with df degrees of freedom
+func chiSquareSurvival(x, df float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return upperIncompleteGamma(df/2, x/2)
+}
+
+// upperIncompleteGamma returns the regularized upper incomplete gamma function Q(a, x)
+func upperIncompleteGamma(a, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	lgammaA, _ := math.Lgamma(a)
+	prefix := math.Exp(a*math.Log(x) - x - lgammaA)
+	if x < a+1 {
+		// Series expansion of the lower function
+		sum := 1 / a
+		term := sum
+		for n := 1; n < maxIterations; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*epsilon {
+				break
+			}
+		}
+		return math.Max(0, 1-sum*prefix)
+	}
+	// Continued fraction using the modified Lentz method
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < maxIterations; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return prefix * h
+}
+
+// buildStatistics computes confidence intervals and significance tests for the ranked results,
+// adding the confidence interval and significance tier to each row
+func buildStatistics(results []*wineResult, method PostHocMethod) *ResultStatistics {
+	for _, result := range results {
+		if lower, upper, ok := confidenceInterval(result.scores.totals); ok {
+			result.row["ciLower"] = roundScore(lower)
+			result.row["ciUpper"] = roundScore(upper)
+		}
+	}
+	stats := &ResultStatistics{
+		Alpha: significanceLevel,
+	}
+	// The Friedman test needs complete blocks, so only participants who rated every wine are included
+	blocks := make([][]float64, 0)
+	if len(results) > 0 {
+		for username := range results[0].byUsername {
+			block := make([]float64, 0, len(results))
+			for _, result := range results {
+				score, ok := result.byUsername[username]
+				if !ok {
+					break
+				}
+				block = append(block, score)
+			}
+			if len(block) == len(results) {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+	if len(results) < 2 || len(blocks) < 2 {
+		return stats
+	}
+	statistic, meanRanks, pValue := friedman(blocks)
+	stats.Friedman = &FriedmanResult{
+		Statistic:        roundStatistic(statistic),
+		DegreesOfFreedom: len(results) - 1,
+		PValue:           roundStatistic(pValue),
+		Participants:     len(blocks),
+		Significant:      pValue < significanceLevel,
+	}
+	criticalDifference, ok := nemenyiCriticalDifference(len(results), len(blocks))
+	if method == PostHocNemenyi && !ok {
+		method = PostHocWilcoxon
+	}
+	postHoc := &PostHocResult{
+		Method:      string(method),
+		Comparisons: make([]*PairwiseComparison, 0),
+	}
+	significant := make(map[[2]int]bool)
+	switch method {
+	case PostHocNemenyi:
+		roundedCriticalDifference := roundStatistic(criticalDifference)
+		postHoc.CriticalDifference = &roundedCriticalDifference
+		for i := 0; i < len(results); i++ {
+			for j := i + 1; j < len(results); j++ {
+				rankDifference := math.Abs(meanRanks[i] - meanRanks[j])
+				roundedRankDifference := roundStatistic(rankDifference)
+				significant[[2]int{i, j}] = rankDifference >= criticalDifference
+				postHoc.Comparisons = append(postHoc.Comparisons, &PairwiseComparison{
+					WineAID:        results[i].wine.WineID,
+					WineBID:        results[j].wine.WineID,
+					RankDifference: &roundedRankDifference,
+					Significant:    significant[[2]int{i, j}],
+				})
+			}
+		}
+	case PostHocWilcoxon:
+		pairs := make([][2]int, 0)
+		pValues := make([]float64, 0)
+		for i := 0; i < len(results); i++ {
+			for j := i + 1; j < len(results); j++ {
+				a := make([]float64, 0)
+				b := make([]float64, 0)
+				for username, score := range results[i].byUsername {
+					if other, ok := results[j].byUsername[username]; ok {
+						a = append(a, score)
+						b = append(b, other)
+					}
+				}
+				pairs = append(pairs, [2]int{i, j})
+				pValues = append(pValues, wilcoxonSignedRank(a, b))
+			}
+		}
+		for i, adjusted := range holmAdjust(pValues) {
+			pair := pairs[i]
+			roundedPValue := roundStatistic(adjusted)
+			significant[pair] = adjusted < significanceLevel
+			postHoc.Comparisons = append(postHoc.Comparisons, &PairwiseComparison{
+				WineAID:     results[pair[0]].wine.WineID,
+				WineBID:     results[pair[1]].wine.WineID,
+				PValue:      &roundedPValue,
+				Significant: significant[pair],
+			})
+		}
+	}
+	stats.PostHoc = postHoc
+	// Walk down the leaderboard and start a new tier whenever a wine is
+	// significantly different from the first wine of the current tier
+	tier := 1
+	leader := 0
+	for i, result := range results {
+		if i > 0 && significant[[2]int{leader, i}] {
+			tier++
+			leader = i
+		}
+		result.row["tier"] = tier
+	}
+	return stats
+}
+
+func roundStatistic(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
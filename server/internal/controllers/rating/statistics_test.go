@@ -0,0 +1,325 @@
+package rating
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
+)
+
+const tolerance = 1e-6
+
+func assertClose(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > tolerance {
+		t.Fatalf("%s was %v, want %v", name, got, want)
+	}
+}
+
+func assertAllClose(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s was %v, want %v", name, got, want)
+	}
+	for i := range got {
+		assertClose(t, fmt.Sprintf("%s[%d]", name, i), got[i], want[i])
+	}
+}
+
+func TestAverageRanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   []float64
+	}{
+		{name: "distinct", values: []float64{30, 10, 20}, want: []float64{3, 1, 2}},
+		{name: "tied pair", values: []float64{10, 20, 20, 30}, want: []float64{1, 2.5, 2.5, 4}},
+		{name: "all equal", values: []float64{7, 7, 7}, want: []float64{2, 2, 2}},
+		{name: "single", values: []float64{5}, want: []float64{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertAllClose(t, "ranks", averageRanks(tt.values), tt.want)
+		})
+	}
+}
+
+func TestChiSquareSurvival(t *testing.T) {
+	// Critical values at alpha 0.05 from the standard chi-square table
+	tests := []struct {
+		x    float64
+		df   float64
+		want float64
+	}{
+		{x: 3.841459, df: 1, want: 0.05},
+		{x: 5.991465, df: 2, want: 0.05},
+		{x: 9.487729, df: 4, want: 0.05},
+		{x: 18.307038, df: 10, want: 0.05},
+		{x: 6.634897, df: 1, want: 0.01},
+		{x: 0, df: 3, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v with %v df", tt.x, tt.df), func(t *testing.T) {
+			assertClose(t, "p-value", chiSquareSurvival(tt.x, tt.df), tt.want)
+		})
+	}
+}
+
+func TestFriedman(t *testing.T) {
+	tests := []struct {
+		name      string
+		blocks    [][]float64
+		statistic float64
+		meanRanks []float64
+		pValue    float64
+	}{
+		{
+			// Every participant agrees, so the statistic is n(k-1) and with 2 df the p-value is exp(-x/2)
+			name:      "identical orderings",
+			blocks:    [][]float64{{1, 2, 3}, {1, 2, 3}, {1, 2, 3}},
+			statistic: 6,
+			meanRanks: []float64{1, 2, 3},
+			pValue:    math.Exp(-3),
+		},
+		{
+			// Uncorrected statistic 5.1667 divided by the tie correction 1 - 6/72
+			name:      "tie within a block",
+			blocks:    [][]float64{{1, 2, 2}, {1, 2, 3}, {1, 2, 3}},
+			statistic: 62.0 / 11,
+			meanRanks: []float64{1, 6.5 / 3, 8.5 / 3},
+			pValue:    math.Exp(-31.0 / 11),
+		},
+		{
+			name:      "opposite orderings",
+			blocks:    [][]float64{{1, 2}, {2, 1}},
+			statistic: 0,
+			meanRanks: []float64{1.5, 1.5},
+			pValue:    1,
+		},
+		{
+			name:      "all scores equal",
+			blocks:    [][]float64{{5, 5, 5}, {5, 5, 5}},
+			statistic: 0,
+			meanRanks: []float64{2, 2, 2},
+			pValue:    1,
+		},
+		{
+			name:      "single participant",
+			blocks:    [][]float64{{3, 1, 2}},
+			statistic: 2,
+			meanRanks: []float64{3, 1, 2},
+			pValue:    math.Exp(-1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statistic, meanRanks, pValue := friedman(tt.blocks)
+			assertClose(t, "statistic", statistic, tt.statistic)
+			assertAllClose(t, "mean ranks", meanRanks, tt.meanRanks)
+			assertClose(t, "p-value", pValue, tt.pValue)
+		})
+	}
+}
+
+func TestNemenyiCriticalDifference(t *testing.T) {
+	tests := []struct {
+		k, n int
+		want float64
+		ok   bool
+	}{
+		// Studentized range values divided by sqrt(2), as tabled by Demšar (2006)
+		{k: 2, n: 6, want: 1.960 * math.Sqrt(6.0/36), ok: true},
+		{k: 3, n: 10, want: 2.343 * math.Sqrt(12.0/60), ok: true},
+		{k: 5, n: 8, want: 2.728 * math.Sqrt(30.0/48), ok: true},
+		{k: 20, n: 4, want: 3.544 * math.Sqrt(420.0/24), ok: true},
+		{k: 1, n: 10, ok: false},
+		{k: 21, n: 10, ok: false},
+		{k: 3, n: 0, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d wines and %d participants", tt.k, tt.n), func(t *testing.T) {
+			got, ok := nemenyiCriticalDifference(tt.k, tt.n)
+			if ok != tt.ok {
+				t.Fatalf("ok was %v, want %v", ok, tt.ok)
+			}
+			assertClose(t, "critical difference", got, tt.want)
+		})
+	}
+}
+
+func TestWilcoxonSignedRank(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		// With every difference positive only 1 of the 2^n sign assignments is as extreme
+		{name: "five positive differences", a: []float64{2, 4, 6, 8, 10}, b: []float64{1, 2, 3, 4, 5}, want: 2.0 / 32},
+		{name: "six positive differences", a: []float64{2, 4, 6, 8, 10, 12}, b: []float64{1, 2, 3, 4, 5, 6}, want: 2.0 / 64},
+		// W+ is 10 of 15, and 10 of the 32 assignments have W+ >= 10
+		{name: "mixed signs", a: []float64{1, 2, 3, 4, 0}, b: []float64{0, 0, 0, 0, 5}, want: 20.0 / 32},
+		{name: "tied differences", a: []float64{1, 1, 2, 3}, b: []float64{0, 0, 0, 0}, want: 2.0 / 16},
+		// The zero difference is dropped, leaving ranks 1.5, 1.5 and 3 with W+ of 4.5
+		{name: "zero and tied differences", a: []float64{5, 1, 0, 3}, b: []float64{5, 0, 1, 0}, want: 6.0 / 8},
+		{name: "all equal", a: []float64{4, 4, 4}, b: []float64{4, 4, 4}, want: 1},
+		{name: "single participant", a: []float64{4}, b: []float64{2}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertClose(t, "p-value", wilcoxonSignedRank(tt.a, tt.b), tt.want)
+		})
+	}
+}
+
+func TestHolmAdjust(t *testing.T) {
+	tests := []struct {
+		name    string
+		pValues []float64
+		want    []float64
+	}{
+		{name: "monotone step up", pValues: []float64{0.01, 0.04, 0.03, 0.005}, want: []float64{0.03, 0.06, 0.06, 0.02}},
+		{name: "capped at one", pValues: []float64{0.5, 0.6}, want: []float64{1, 1}},
+		{name: "ties", pValues: []float64{0.01, 0.01, 0.01}, want: []float64{0.03, 0.03, 0.03}},
+		{name: "single", pValues: []float64{0.02}, want: []float64{0.02}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertAllClose(t, "adjusted", holmAdjust(tt.pValues), tt.want)
+		})
+	}
+}
+
+func TestConfidenceInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		lower  float64
+		upper  float64
+		ok     bool
+	}{
+		// t(4) = 2.776, so the margin is 2.776 * sqrt(2.5) / sqrt(5)
+		{name: "five values", values: []float64{1, 2, 3, 4, 5}, lower: 3 - 2.776*math.Sqrt(0.5), upper: 3 + 2.776*math.Sqrt(0.5), ok: true},
+		{name: "two values", values: []float64{4, 6}, lower: 5 - 12.706, upper: 5 + 12.706, ok: true},
+		{name: "all equal", values: []float64{3, 3, 3}, lower: 3, upper: 3, ok: true},
+		{name: "single value", values: []float64{3}, ok: false},
+		{name: "no values", values: []float64{}, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lower, upper, ok := confidenceInterval(tt.values)
+			if ok != tt.ok {
+				t.Fatalf("ok was %v, want %v", ok, tt.ok)
+			}
+			assertClose(t, "lower", lower, tt.lower)
+			assertClose(t, "upper", upper, tt.upper)
+		})
+	}
+}
+
+// newStatisticsResults builds leaderboard rows from scores[wine][participant]
+func newStatisticsResults(scores [][]float64) []*wineResult {
+	results := make([]*wineResult, len(scores))
+	for i, wineScores := range scores {
+		result := &wineResult{
+			wine:       &wine.Wine{WineID: fmt.Sprintf("wine-%d", i)},
+			scores:     newWineScores(),
+			byUsername: make(map[string]float64),
+			row:        make(map[string]any),
+		}
+		for j, score := range wineScores {
+			result.scores.add(&Rating{TotalRating: score})
+			result.byUsername[fmt.Sprintf("user-%d", j)] = score
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// orderedScores returns scores for k wines where every one of n participants prefers the earlier wine
+func orderedScores(k, n int) [][]float64 {
+	scores := make([][]float64, k)
+	for i := range scores {
+		scores[i] = make([]float64, n)
+		for j := range scores[i] {
+			scores[i][j] = float64(30 - 5*i + j%3)
+		}
+	}
+	return scores
+}
+
+func TestBuildStatisticsTiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		scores   [][]float64
+		method   PostHocMethod
+		friedman bool
+		postHoc  PostHocMethod
+		tiers    []int
+	}{
+		// The mean ranks are 3, 2 and 1, and the critical difference is 1.048 with 10 participants
+		{name: "nemenyi groups neighbours", scores: orderedScores(3, 10), method: PostHocNemenyi, friedman: true, postHoc: PostHocNemenyi, tiers: []int{1, 1, 2}},
+		// With 12 participants the critical difference drops to 0.957
+		{name: "nemenyi separates neighbours", scores: orderedScores(3, 12), method: PostHocNemenyi, friedman: true, postHoc: PostHocNemenyi, tiers: []int{1, 2, 3}},
+		// Every pair has p = 2/1024, which stays below alpha after the Holm correction
+		{name: "wilcoxon separates neighbours", scores: orderedScores(3, 10), method: PostHocWilcoxon, friedman: true, postHoc: PostHocWilcoxon, tiers: []int{1, 2, 3}},
+		{name: "nemenyi falls back past its table", scores: orderedScores(21, 3), method: PostHocNemenyi, friedman: true, postHoc: PostHocWilcoxon},
+		{name: "all scores equal", scores: [][]float64{{20, 20, 20}, {20, 20, 20}}, method: PostHocNemenyi, postHoc: PostHocNemenyi, tiers: []int{1, 1}},
+		{name: "single participant", scores: [][]float64{{30}, {20}, {10}}, method: PostHocNemenyi},
+		{name: "single wine", scores: [][]float64{{30, 20, 10}}, method: PostHocNemenyi},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := newStatisticsResults(tt.scores)
+			stats := buildStatistics(results, tt.method)
+			if stats.Alpha != significanceLevel {
+				t.Fatalf("alpha was %v, want %v", stats.Alpha, significanceLevel)
+			}
+			if tt.postHoc == "" {
+				if stats.Friedman != nil || stats.PostHoc != nil {
+					t.Fatalf("ran significance tests without enough data")
+				}
+				for _, result := range results {
+					if _, ok := result.row["tier"]; ok {
+						t.Fatalf("%s was given a tier without enough data", result.wine.WineID)
+					}
+				}
+				return
+			}
+			if stats.Friedman == nil || stats.PostHoc == nil {
+				t.Fatalf("did not run the significance tests")
+			}
+			if stats.Friedman.Significant != tt.friedman {
+				t.Fatalf("friedman significance was %v, want %v", stats.Friedman.Significant, tt.friedman)
+			}
+			if stats.Friedman.DegreesOfFreedom != len(tt.scores)-1 {
+				t.Fatalf("friedman had %d df, want %d", stats.Friedman.DegreesOfFreedom, len(tt.scores)-1)
+			}
+			if stats.PostHoc.Method != string(tt.postHoc) {
+				t.Fatalf("post hoc method was %s, want %s", stats.PostHoc.Method, tt.postHoc)
+			}
+			if want := len(tt.scores) * (len(tt.scores) - 1) / 2; len(stats.PostHoc.Comparisons) != want {
+				t.Fatalf("made %d comparisons, want %d", len(stats.PostHoc.Comparisons), want)
+			}
+			for i, want := range tt.tiers {
+				if tier := results[i].row["tier"]; tier != want {
+					t.Fatalf("%s was in tier %v, want %d", results[i].wine.WineID, tier, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildStatisticsConfidenceIntervals(t *testing.T) {
+	results := newStatisticsResults([][]float64{{1, 2, 3, 4, 5}, {4}})
+	buildStatistics(results, PostHocNemenyi)
+	if lower := results[0].row["ciLower"]; lower != roundScore(3-2.776*math.Sqrt(0.5)) {
+		t.Fatalf("lower bound was %v", lower)
+	}
+	if upper := results[0].row["ciUpper"]; upper != roundScore(3+2.776*math.Sqrt(0.5)) {
+		t.Fatalf("upper bound was %v", upper)
+	}
+	if _, ok := results[1].row["ciLower"]; ok {
+		t.Fatalf("wine with a single rating was given a confidence interval")
+	}
+}
@@ -0,0 +1,83 @@
+package rating
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+)
+
+// GetWineDetail lists every rating of a single wine along with its score distribution.
+// Participants may only see the detail once results are shared, and never see who gave each rating.
+func (c *Controller) GetWineDetail(ctx context.Context, gameID, wineID string, isAdmin bool) (*WineDetail, error) {
+	ctx, span := tracing.Start(ctx, "rating.GetWineDetail")
+	defer span.End()
+	if !isAdmin {
+		game, err := c.gameController.GetSingle(ctx, gameID)
+		if err != nil {
+			return nil, fmt.Errorf("[controllers.rating.GetWineDetail] could not get game: %w", err)
+		}
+		if !game.AreResultsShared {
+			return nil, fmt.Errorf("[controllers.rating.GetWineDetail] results are not shared: %w", werrors.ErrForbidden)
+		}
+	}
+	wines, err := c.wineController.GetAllWines(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.GetWineDetail] could not get all wines: %w", err)
+	}
+	var detail *WineDetail
+	for _, wine := range wines {
+		if wine.WineID == wineID {
+			detail = &WineDetail{
+				WineID:   wine.WineID,
+				WineName: wine.WineName,
+				WineCode: wine.WineCode,
+				WineYear: wine.WineYear,
+			}
+		}
+	}
+	if detail == nil {
+		return nil, fmt.Errorf("[controllers.rating.GetWineDetail] no wine found in game: %w", werrors.ErrNotFound)
+	}
+	ratings, err := c.GetAllByGameID(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[controllers.rating.GetWineDetail] could not get all ratings: %w", err)
+	}
+	scores := newWineScores()
+	detail.Ratings = make([]*WineDetailRating, 0)
+	for _, rating := range ratings {
+		if rating.WineID != wineID || rating.isEmpty() {
+			continue
+		}
+		scores.add(rating)
+		detailRating := &WineDetailRating{
+			SightRating:   rating.SightRating,
+			AromaRating:   rating.AromaRating,
+			TasteRating:   rating.TasteRating,
+			OverallRating: rating.OverallRating,
+			TotalRating:   rating.TotalRating,
+			Comments:      rating.Comments,
+		}
+		if isAdmin {
+			detailRating.Username = rating.Username
+		}
+		detail.Ratings = append(detail.Ratings, detailRating)
+	}
+	sort.SliceStable(detail.Ratings, func(i, j int) bool {
+		return detail.Ratings[i].TotalRating > detail.Ratings[j].TotalRating
+	})
+	detail.Avg = roundScore(mean(scores.totals))
+	detail.StdDev = roundScore(standardDeviation(scores.totals))
+	detail.Histogram = histogram(scores.totals)
+	detail.Categories = make([]*CategorySummary, 0, len(Categories))
+	for _, category := range Categories {
+		detail.Categories = append(detail.Categories, &CategorySummary{
+			Category: category,
+			Avg:      roundScore(scores.categoryMean(category.Key)),
+			StdDev:   roundScore(standardDeviation(scores.categories[category.Key])),
+		})
+	}
+	return detail, nil
+}
@@ -7,75 +7,58 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
 	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
 	"github.com/jmoiron/sqlx"
 )
 
-func (s *Controller) signInToGame(ctx context.Context, username, passcode string) (*SignInResponse, error) {
-	gameID, err := s.getGameID(passcode)
-	if err != nil {
-		return nil, fmt.Errorf("[session.signInToGame] failed to get game id: %w", err)
-	}
-	var token string
+func (s *Controller) signInToGame(ctx context.Context, username, passcode string, client Client) (*SignInResponse, error) {
+	var res *SignInResponse
 	if err := s.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
-		userID, err := s.getUserIDByUsernameTx(ctx, tx, username)
-		if err != nil {
-			if !errors.Is(err, werrors.ErrNotFound) {
-				return fmt.Errorf("[session.signInToGame] failed to get user by username: %w", err)
-			}
-		}
-		if userID == "" {
-			userID, err = s.createUserTx(ctx, tx, username, gameID)
-			if err != nil {
-				return fmt.Errorf("[session.signInToGame] failed to create participant: %w", err)
-			}
-		}
-		token, err = s.signUserToken(userID, gameID)
+		game, err := s.gameController.GetJoinableByCodeTx(ctx, tx, passcode)
 		if err != nil {
-			return fmt.Errorf("[session.signInToGame] failed to sign token: %w", err)
+			return fmt.Errorf("[session.signInToGame] failed to get game: %w", err)
 		}
-		return nil
+		res, err = s.joinGameTx(ctx, tx, game, username, client)
+		return err
 	}); err != nil {
-		return nil, fmt.Errorf("[session.signInToGame] failed to create participant: %w", err)
+		return nil, fmt.Errorf("[session.signInToGame] failed to sign in to game: %w", err)
 	}
-	return &SignInResponse{
-		JWT:    token,
-		GameID: &gameID,
-	}, nil
+	return res, nil
 }
 
-func (s *Controller) getGameID(passcode string) (string, error) {
-	row := s.db.QueryRow("SELECT game_id FROM game WHERE game_code = $1", passcode)
-	var gameID string
-	if err := row.Scan(&gameID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", fmt.Errorf("[session.getGameID] no game found: %w", werrors.ErrUnauthorized)
+// joinGameTx signs in the participant with the username, adding them to the game when they are new
+func (s *Controller) joinGameTx(ctx context.Context, tx *sqlx.Tx, game *game.Game, username string, client Client) (*SignInResponse, error) {
+	gameID := game.GameID
+	userID, err := s.getUserIDByUsernameTx(ctx, tx, username, gameID)
+	if err != nil {
+		if !errors.Is(err, werrors.ErrNotFound) {
+			return nil, fmt.Errorf("[session.joinGameTx] failed to get user by username: %w", err)
 		}
-		return "", err
 	}
-	return gameID, nil
-}
-
-func (s *Controller) signUserToken(userID, gameID string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"aud":    "rating-party",
-		"exp":    time.Now().Add(24 * time.Hour).Unix(),
-		"iss":    "rating-party",
-		"iat":    time.Now().Unix(),
-		"sub":    userID,
-		"gameId": gameID,
-	})
-	signedToken, err := token.SignedString([]byte(s.cfg.AdminJWTSecret))
+	// Only new participants are held to the join settings so nobody is locked out mid party
+	if userID == "" {
+		if !game.CodeActive(game.IsRunning, time.Now()) {
+			return nil, fmt.Errorf("[session.joinGameTx] game code is not active: %w", werrors.ErrUnauthorized)
+		}
+		if err := s.gameController.ClaimJoinTx(ctx, tx, gameID); err != nil {
+			return nil, fmt.Errorf("[session.joinGameTx] failed to join game: %w", err)
+		}
+		userID, err = s.createUserTx(ctx, tx, username, gameID)
+		if err != nil {
+			return nil, fmt.Errorf("[session.joinGameTx] failed to create participant: %w", err)
+		}
+	}
+	res, err := s.issueTokensTx(ctx, tx, userID, gameID, client)
 	if err != nil {
-		return "", fmt.Errorf("[session.signUserToken] failed to sign token: %w", err)
+		return nil, fmt.Errorf("[session.joinGameTx] failed to issue tokens: %w", err)
 	}
-	return signedToken, nil
+	return res, nil
 }
 
-func (s *Controller) getUserIDByUsernameTx(ctx context.Context, tx *sqlx.Tx, username string) (string, error) {
-	row := tx.QueryRowxContext(ctx, "SELECT participant_id FROM participant WHERE username = $1", username)
+func (s *Controller) getUserIDByUsernameTx(ctx context.Context, tx *sqlx.Tx, username, gameID string) (string, error) {
+	row := tx.QueryRowxContext(ctx, "SELECT participant_id FROM participant WHERE username = $1 AND game_id = $2 AND deleted_at IS NULL", username, gameID)
 	var userID string
 	if err := row.Scan(&userID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -0,0 +1,127 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/jacobtie/rating-party/server/internal/platform/jwtkeys"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
+)
+
+const inviteAudience = "rating-party-invite"
+
+type Invite struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	GameID    string    `json:"gameId"`
+	Username  string    `json:"username,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CreateInvite signs a link that joins the game without typing the game code. When a username is given the
+// link always signs in as that participant. The link does not contain the game code, but it is tied to it,
+// so regenerating the code also cancels every invite handed out for it.
+func (s *Controller) CreateInvite(ctx context.Context, gameID, username string, validFor time.Duration) (*Invite, error) {
+	ctx, span := tracing.Start(ctx, "session.CreateInvite")
+	defer span.End()
+	game, err := s.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[session.CreateInvite] failed to get game: %w", err)
+	}
+	if validFor <= 0 {
+		validFor = s.cfg.InviteTTL
+	}
+	username = strings.TrimSpace(username)
+	expiresAt := time.Now().Add(validFor).UTC().Truncate(time.Second)
+	claims := jwt.MapClaims{
+		"aud":         inviteAudience,
+		"exp":         expiresAt.Unix(),
+		"iss":         "rating-party",
+		"iat":         time.Now().Unix(),
+		"gameId":      gameID,
+		"codeVersion": game.CodeVersion,
+	}
+	if username != "" {
+		claims["username"] = username
+	}
+	keys, err := jwtkeys.Get()
+	if err != nil {
+		return nil, fmt.Errorf("[session.CreateInvite] failed to get signing keys: %w", err)
+	}
+	token, err := keys.Sign(claims)
+	if err != nil {
+		return nil, fmt.Errorf("[session.CreateInvite] failed to sign invite: %w", err)
+	}
+	query := url.Values{}
+	query.Set("invite", token)
+	return &Invite{
+		Token:     token,
+		URL:       fmt.Sprintf("%s/?%s", strings.TrimRight(s.cfg.Web.PublicURL, "/"), query.Encode()),
+		GameID:    gameID,
+		Username:  username,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// SignInWithInvite signs in with an invite link. The username is only used when the invite is not bound to one.
+func (s *Controller) SignInWithInvite(ctx context.Context, inviteToken, username string, client Client) (*SignInResponse, error) {
+	ctx, span := tracing.Start(ctx, "session.SignInWithInvite")
+	defer span.End()
+	gameID, codeVersion, boundUsername, err := s.parseInvite(inviteToken)
+	if err != nil {
+		return nil, fmt.Errorf("[session.SignInWithInvite] invalid invite: %w", err)
+	}
+	if boundUsername != "" {
+		username = boundUsername
+	}
+	if strings.TrimSpace(username) == "" {
+		return nil, fmt.Errorf("[session.SignInWithInvite] username is required: %w", werrors.ErrBadRequest)
+	}
+	// The invite is signed so there is nothing to guess, unlike the game code it is not held to the sign in limiter
+	var res *SignInResponse
+	if err := s.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		game, err := s.gameController.GetJoinableByIDTx(ctx, tx, gameID, codeVersion)
+		if err != nil {
+			return fmt.Errorf("[session.SignInWithInvite] failed to get game: %w", err)
+		}
+		res, err = s.joinGameTx(ctx, tx, game, username, client)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("[session.SignInWithInvite] failed to sign in: %w", err)
+	}
+	return res, nil
+}
+
+// parseInvite returns the game, the version of its code and the bound username from a valid invite
+func (s *Controller) parseInvite(inviteToken string) (string, int, string, error) {
+	keys, err := jwtkeys.Get()
+	if err != nil {
+		return "", 0, "", fmt.Errorf("[session.parseInvite] failed to get signing keys: %w", err)
+	}
+	token, err := jwt.Parse(inviteToken, keys.Keyfunc)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("[session.parseInvite] failed to parse invite: %v: %w", err, werrors.ErrUnauthorized)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !claims.VerifyAudience(inviteAudience, true) {
+		return "", 0, "", fmt.Errorf("[session.parseInvite] token is not an invite: %w", werrors.ErrUnauthorized)
+	}
+	// Invites always expire, unlike tokens where a missing exp would pass validation
+	if _, ok := claims["exp"]; !ok {
+		return "", 0, "", fmt.Errorf("[session.parseInvite] invite has no expiry: %w", werrors.ErrUnauthorized)
+	}
+	gameID, _ := claims["gameId"].(string)
+	// Numbers in the claims are decoded as float64
+	codeVersion, ok := claims["codeVersion"].(float64)
+	if gameID == "" || !ok {
+		return "", 0, "", fmt.Errorf("[session.parseInvite] invite has no game: %w", werrors.ErrUnauthorized)
+	}
+	username, _ := claims["username"].(string)
+	return gameID, int(codeVersion), username, nil
+}
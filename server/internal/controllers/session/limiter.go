@@ -0,0 +1,222 @@
+package session
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/config"
+)
+
+const (
+	LockoutKindIP            = "ip"
+	LockoutKindUsername      = "username"
+	LockoutKindUsernameDelay = "usernameDelay"
+)
+
+// Lockout is a client that is currently blocked from signing in
+type Lockout struct {
+	Kind        string    `json:"kind"`
+	Value       string    `json:"value"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+type failureRecord struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+	// nextAttempt is when the next attempt for a username delay may be checked
+	nextAttempt time.Time
+}
+
+// limiter tracks failed sign ins in memory. Records are forgotten once they go quiet for the reset window,
+// so a restart only ever gives an attacker back the free attempts.
+type limiter struct {
+	mu        sync.Mutex
+	cfg       *config.Config
+	records   map[string]*failureRecord
+	lastSweep time.Time
+}
+
+func newLimiter(cfg *config.Config) *limiter {
+	return &limiter{
+		cfg:     cfg,
+		records: make(map[string]*failureRecord),
+	}
+}
+
+// limiterKeys locks out the client address, and the username only as tried from that address. The username is not
+// locked out on its own, since anyone could then keep a username such as admin locked out without knowing its passcode.
+// Failures for the username from every address are counted under usernameDelayKey instead, which only slows attempts down.
+func limiterKeys(ip, username string) []string {
+	return []string{
+		LockoutKindIP + ":" + ip,
+		LockoutKindUsername + ":" + usernameKey(ip, username),
+	}
+}
+
+// usernameKey is shown to the admin as the lockout value, for example alice@203.0.113.7
+func usernameKey(ip, username string) string {
+	return normalizeUsername(username) + "@" + ip
+}
+
+func usernameDelayKey(username string) string {
+	return LockoutKindUsernameDelay + ":" + normalizeUsername(username)
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// backoff doubles the base wait for every failure past the free attempts, up to the max
+func backoff(extra int, base, max time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < extra && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// retryAfter returns how long the client must wait before it may try to sign in, or zero if it may try now
+func (l *limiter) retryAfter(ip, username string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var wait time.Duration
+	for _, key := range limiterKeys(ip, username) {
+		record, ok := l.records[key]
+		if !ok {
+			continue
+		}
+		if remaining := record.lockedUntil.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// fail records a failed sign in against the client address and username and returns the resulting lockout
+func (l *limiter) fail(ip, username string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	limit := l.cfg.SignInLimit
+	var wait time.Duration
+	for _, key := range limiterKeys(ip, username) {
+		record, ok := l.records[key]
+		if !ok || now.Sub(record.lastFailure) > limit.ResetAfter {
+			record = &failureRecord{}
+			l.records[key] = record
+		}
+		record.failures++
+		record.lastFailure = now
+		if extra := record.failures - limit.FreeAttempts; extra > 0 {
+			lockout := backoff(extra, limit.BaseLockout, limit.MaxLockout)
+			record.lockedUntil = now.Add(lockout)
+			if lockout > wait {
+				wait = lockout
+			}
+		}
+	}
+	key := usernameDelayKey(username)
+	record, ok := l.records[key]
+	if !ok || now.Sub(record.lastFailure) > limit.ResetAfter {
+		record = &failureRecord{}
+		l.records[key] = record
+	}
+	record.failures++
+	record.lastFailure = now
+	return wait
+}
+
+// delay spaces out attempts for a username once it has failed too often from any address, so guessing the admin
+// passcode from many addresses is slowed down as much as guessing from one. It returns how long the attempt must wait
+// before it is checked. Waiting rather than locking out means the real admin can still sign in while it happens.
+// When the wait would be longer than SIGNIN_MAX_USERNAME_DELAY the attempt is turned away and false is returned.
+func (l *limiter) delay(username string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit := l.cfg.SignInLimit
+	record, ok := l.records[usernameDelayKey(username)]
+	if !ok || now.Sub(record.lastFailure) > limit.ResetAfter {
+		return 0, true
+	}
+	extra := record.failures - limit.FreeAttempts
+	if extra <= 0 {
+		return 0, true
+	}
+	start := now
+	if record.nextAttempt.After(start) {
+		start = record.nextAttempt
+	}
+	wait := start.Sub(now)
+	if wait > limit.MaxUsernameDelay {
+		return wait, false
+	}
+	record.nextAttempt = start.Add(backoff(extra, limit.BaseLockout, limit.MaxUsernameDelay))
+	return wait, true
+}
+
+// succeed clears the failures for a username. The address keeps its failures so that signing in to a game
+// the attacker already knows does not reset their guesses at other codes.
+func (l *limiter) succeed(ip, username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.records, limiterKeys(ip, username)[1])
+	delete(l.records, usernameDelayKey(username))
+}
+
+func (l *limiter) lockouts(now time.Time) []*Lockout {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lockouts := make([]*Lockout, 0)
+	for key, record := range l.records {
+		// A username delay is listed until its queue of attempts has cleared
+		lockedUntil := record.lockedUntil
+		if record.nextAttempt.After(lockedUntil) {
+			lockedUntil = record.nextAttempt
+		}
+		if !lockedUntil.After(now) {
+			continue
+		}
+		kind, value, _ := strings.Cut(key, ":")
+		lockouts = append(lockouts, &Lockout{
+			Kind:        kind,
+			Value:       value,
+			Failures:    record.failures,
+			LockedUntil: lockedUntil,
+		})
+	}
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].LockedUntil.After(lockouts[j].LockedUntil)
+	})
+	return lockouts
+}
+
+func (l *limiter) unlock(kind, value string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := kind + ":" + value
+	if _, ok := l.records[key]; !ok {
+		return false
+	}
+	delete(l.records, key)
+	return true
+}
+
+// sweep drops records that have gone quiet, at most once a minute
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, record := range l.records {
+		if now.Sub(record.lastFailure) > l.cfg.SignInLimit.ResetAfter && !record.lockedUntil.After(now) && !record.nextAttempt.After(now) {
+			delete(l.records, key)
+		}
+	}
+}
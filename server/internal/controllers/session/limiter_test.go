@@ -0,0 +1,126 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/config"
+)
+
+func newTestLimiter() *limiter {
+	cfg := &config.Config{}
+	cfg.SignInLimit.FreeAttempts = 3
+	cfg.SignInLimit.BaseLockout = time.Second
+	cfg.SignInLimit.MaxLockout = time.Minute
+	cfg.SignInLimit.MaxUsernameDelay = 4 * time.Second
+	cfg.SignInLimit.ResetAfter = time.Hour
+	return newLimiter(cfg)
+}
+
+func TestLimiterLocksOutAddress(t *testing.T) {
+	l := newTestLimiter()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if wait := l.fail("203.0.113.7", "alice", now); wait != 0 {
+			t.Fatalf("free attempt %d locked out for %s", i+1, wait)
+		}
+	}
+	if wait := l.fail("203.0.113.7", "alice", now); wait != time.Second {
+		t.Fatalf("first lockout was %s, want 1s", wait)
+	}
+	if wait := l.fail("203.0.113.7", "alice", now); wait != 2*time.Second {
+		t.Fatalf("second lockout was %s, want 2s", wait)
+	}
+	if wait := l.retryAfter("203.0.113.7", "bob", now); wait != 2*time.Second {
+		t.Fatalf("address was locked out for %s, want 2s", wait)
+	}
+	if wait := l.retryAfter("198.51.100.1", "alice", now); wait != 0 {
+		t.Fatalf("username was locked out from another address for %s", wait)
+	}
+}
+
+func TestLimiterSlowsUsernameAcrossAddresses(t *testing.T) {
+	l := newTestLimiter()
+	now := time.Now()
+	// Each address stays within its free attempts, so only the username delay can catch the attack
+	for i := 0; i < 10; i++ {
+		ip := fmt.Sprintf("203.0.113.%d", i)
+		if wait := l.retryAfter(ip, "admin", now); wait != 0 {
+			t.Fatalf("address %s was locked out for %s", ip, wait)
+		}
+		l.fail(ip, "admin", now)
+	}
+
+	tests := []struct {
+		name string
+		wait time.Duration
+		ok   bool
+	}{
+		// 7 failures past the free attempts space attempts by the max delay of 4s
+		{name: "first attempt goes straight through", wait: 0, ok: true},
+		{name: "second attempt waits for the first", wait: 4 * time.Second, ok: true},
+		{name: "third attempt would wait too long", wait: 8 * time.Second, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := l.delay("Admin ", now)
+			if wait != tt.wait || ok != tt.ok {
+				t.Fatalf("delay was %s, %v, want %s, %v", wait, ok, tt.wait, tt.ok)
+			}
+		})
+	}
+
+	// The real admin is slowed down but never locked out
+	if wait := l.retryAfter("192.0.2.1", "admin", now); wait != 0 {
+		t.Fatalf("admin was locked out for %s from a new address", wait)
+	}
+	if wait, ok := l.delay("admin", now.Add(8*time.Second)); wait != 0 || !ok {
+		t.Fatalf("delay was %s, %v once the queue cleared, want 0s, true", wait, ok)
+	}
+
+	l.succeed("192.0.2.1", "admin")
+	if wait, ok := l.delay("admin", now.Add(8*time.Second)); wait != 0 || !ok {
+		t.Fatalf("delay was %s, %v after signing in, want 0s, true", wait, ok)
+	}
+}
+
+func TestLimiterUsernameDelayGrows(t *testing.T) {
+	tests := []struct {
+		failures int
+		spacing  time.Duration
+	}{
+		{failures: 3, spacing: 0},
+		{failures: 4, spacing: time.Second},
+		{failures: 5, spacing: 2 * time.Second},
+		{failures: 6, spacing: 4 * time.Second},
+		{failures: 9, spacing: 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d failures", tt.failures), func(t *testing.T) {
+			l := newTestLimiter()
+			now := time.Now()
+			for i := 0; i < tt.failures; i++ {
+				l.fail(fmt.Sprintf("203.0.113.%d", i), "admin", now)
+			}
+			l.delay("admin", now)
+			wait, _ := l.delay("admin", now)
+			if wait != tt.spacing {
+				t.Fatalf("attempts were spaced %s apart, want %s", wait, tt.spacing)
+			}
+		})
+	}
+}
+
+func TestLimiterForgetsQuietFailures(t *testing.T) {
+	l := newTestLimiter()
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		l.fail(fmt.Sprintf("203.0.113.%d", i), "admin", now)
+	}
+	l.delay("admin", now)
+	later := now.Add(2 * time.Hour)
+	if wait, ok := l.delay("admin", later); wait != 0 || !ok {
+		t.Fatalf("delay was %s, %v after the reset window, want 0s, true", wait, ok)
+	}
+}
@@ -2,17 +2,22 @@ package session
 
 import (
 	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
 	"github.com/jacobtie/rating-party/server/internal/platform/db"
 )
 
 type Controller struct {
-	cfg *config.Config
-	db  *db.DB
+	cfg            *config.Config
+	db             *db.DB
+	gameController *game.Controller
+	limiter        *limiter
 }
 
-func NewController(cfg *config.Config, db *db.DB) *Controller {
+func NewController(cfg *config.Config, db *db.DB, gameController *game.Controller) *Controller {
 	return &Controller{
-		cfg: cfg,
-		db:  db,
+		cfg:            cfg,
+		db:             db,
+		gameController: gameController,
+		limiter:        newLimiter(cfg),
 	}
 }
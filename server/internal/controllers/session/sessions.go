@@ -0,0 +1,202 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/platform/revocation"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// expiredSessionRetention keeps expired sessions around for a while so hosts can see who was signed in
+	expiredSessionRetention = 7 * 24 * time.Hour
+	maxUserAgentLength      = 1024
+)
+
+// Session is a signed in admin or participant. The session ID is the jti of every access token issued for it.
+type Session struct {
+	SessionID     string    `json:"sessionId"`
+	IsAdmin       bool      `json:"isAdmin"`
+	GameID        *string   `json:"gameId,omitempty"`
+	ParticipantID *string   `json:"participantId,omitempty"`
+	Username      *string   `json:"username,omitempty"`
+	IPAddress     string    `json:"ipAddress"`
+	UserAgent     string    `json:"userAgent"`
+	CreatedAt     time.Time `json:"createdAt"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+}
+
+// SessionFilter narrows down the sessions listed, an empty filter lists every active session
+type SessionFilter struct {
+	GameID    string
+	AdminOnly bool
+}
+
+// createSession records a new session for a participant, or for the admin when participantID is empty
+func createSession(ctx context.Context, ex sqlx.ExecerContext, participantID, gameID string, client Client, expiresAt time.Time) (string, error) {
+	sessionID := uuid.New().String()
+	// Postgres rejects text that is not valid UTF-8, so the user agent is cleaned up and only cut between characters
+	userAgent := strings.ToValidUTF8(client.UserAgent, "\uFFFD")
+	if len(userAgent) > maxUserAgentLength {
+		end := maxUserAgentLength
+		for end > 0 && !utf8.RuneStart(userAgent[end]) {
+			end--
+		}
+		userAgent = userAgent[:end]
+	}
+	if _, err := ex.ExecContext(ctx, `
+		INSERT INTO user_session (session_id, participant_id, game_id, is_admin, ip_address, user_agent, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7);
+	`, sessionID, nullIfEmpty(participantID), nullIfEmpty(gameID), participantID == "", client.IP, userAgent, expiresAt); err != nil {
+		return "", fmt.Errorf("[session.createSession] failed to create session: %w", err)
+	}
+	return sessionID, nil
+}
+
+func nullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func (s *Controller) GetActiveSessions(ctx context.Context, filter SessionFilter) ([]*Session, error) {
+	ctx, span := tracing.Start(ctx, "session.GetActiveSessions")
+	defer span.End()
+	query := `
+		SELECT
+			user_session.session_id,
+			user_session.is_admin,
+			user_session.game_id,
+			user_session.participant_id,
+			participant.username,
+			user_session.ip_address,
+			user_session.user_agent,
+			COALESCE(user_session.created_at, NOW()),
+			user_session.expires_at
+		FROM
+			user_session
+			LEFT JOIN participant ON participant.participant_id = user_session.participant_id
+		WHERE user_session.revoked_at IS NULL AND user_session.expires_at > $1
+	`
+	args := []any{time.Now().UTC()}
+	if filter.GameID != "" {
+		args = append(args, filter.GameID)
+		query += fmt.Sprintf(` AND user_session.game_id = $%d`, len(args))
+	}
+	if filter.AdminOnly {
+		query += ` AND user_session.is_admin = TRUE`
+	}
+	rows, err := s.db.DB.QueryxContext(ctx, query+` ORDER BY user_session.created_at DESC;`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("[session.GetActiveSessions] failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+	sessions := make([]*Session, 0)
+	for rows.Next() {
+		var session Session
+		var gameID, participantID, username sql.NullString
+		if err := rows.Scan(
+			&session.SessionID,
+			&session.IsAdmin,
+			&gameID,
+			&participantID,
+			&username,
+			&session.IPAddress,
+			&session.UserAgent,
+			&session.CreatedAt,
+			&session.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("[session.GetActiveSessions] failed to scan row: %w", err)
+		}
+		if gameID.Valid {
+			session.GameID = &gameID.String
+		}
+		if participantID.Valid {
+			session.ParticipantID = &participantID.String
+		}
+		if username.Valid {
+			session.Username = &username.String
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, nil
+}
+
+// SignOut revokes the session the token was issued with. Signing out twice is not an error.
+func (s *Controller) SignOut(ctx context.Context, sessionID string) error {
+	ctx, span := tracing.Start(ctx, "session.SignOut")
+	defer span.End()
+	if _, err := s.revokeSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("[session.SignOut] failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeSession signs out someone else's session, for example a lost phone
+func (s *Controller) RevokeSession(ctx context.Context, sessionID string) error {
+	ctx, span := tracing.Start(ctx, "session.RevokeSession")
+	defer span.End()
+	revoked, err := s.revokeSession(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("[session.RevokeSession] failed to revoke session: %w", err)
+	}
+	if !revoked {
+		return fmt.Errorf("[session.RevokeSession] no active session found: %w", werrors.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Controller) revokeSession(ctx context.Context, sessionID string) (bool, error) {
+	row := s.db.DB.QueryRowxContext(ctx, `
+		UPDATE user_session SET revoked_at = NOW() WHERE session_id = $1 AND revoked_at IS NULL RETURNING expires_at;
+	`, sessionID)
+	var expiresAt time.Time
+	if err := row.Scan(&expiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	revocation.Revoke(sessionID, expiresAt)
+	return true, nil
+}
+
+// SyncRevocations reloads the revoked sessions so revocations made by other instances take effect here,
+// and clears out sessions that expired long ago
+func (s *Controller) SyncRevocations(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "session.SyncRevocations")
+	defer span.End()
+	now := time.Now().UTC()
+	rows, err := s.db.DB.QueryxContext(ctx, `
+		SELECT session_id, expires_at FROM user_session WHERE revoked_at IS NOT NULL AND expires_at > $1;
+	`, now)
+	if err != nil {
+		return fmt.Errorf("[session.SyncRevocations] failed to query revoked sessions: %w", err)
+	}
+	defer rows.Close()
+	revoked := make(map[string]time.Time)
+	for rows.Next() {
+		var sessionID string
+		var expiresAt time.Time
+		if err := rows.Scan(&sessionID, &expiresAt); err != nil {
+			return fmt.Errorf("[session.SyncRevocations] failed to scan row: %w", err)
+		}
+		revoked[sessionID] = expiresAt
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("[session.SyncRevocations] failed to read revoked sessions: %w", err)
+	}
+	revocation.Replace(revoked)
+	if _, err := s.db.DB.ExecContext(ctx, `
+		DELETE FROM user_session WHERE expires_at < $1;
+	`, now.Add(-expiredSessionRetention)); err != nil {
+		return fmt.Errorf("[session.SyncRevocations] failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
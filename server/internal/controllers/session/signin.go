@@ -2,40 +2,97 @@ package session
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
 )
 
+// Client identifies where a sign in came from
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
 type SignInResponse struct {
-	JWT     string  `json:"jwt"`
-	IsAdmin *bool   `json:"isAdmin,omitempty"`
-	GameID  *string `json:"gameId,omitempty"`
+	JWT          string  `json:"jwt"`
+	RefreshToken string  `json:"refreshToken"`
+	ExpiresIn    int     `json:"expiresIn"`
+	IsAdmin      *bool   `json:"isAdmin,omitempty"`
+	GameID       *string `json:"gameId,omitempty"`
 }
 
-func (s *Controller) SignIn(ctx context.Context, username, passcode string) (*SignInResponse, error) {
-	if username == "admin" && passcode == s.cfg.AdminPasscode {
-		return s.signAdminToken()
+// SignIn signs in the admin or a game participant. Failed attempts from the client address, or for the
+// username from that address, back off exponentially, and attempts made while locked out are rejected without being checked.
+// Failed attempts for the username from every address add up too, and slow down further attempts for it.
+func (s *Controller) SignIn(ctx context.Context, username, passcode string, client Client) (*SignInResponse, error) {
+	ctx, span := tracing.Start(ctx, "session.SignIn")
+	defer span.End()
+	if wait := s.limiter.retryAfter(client.IP, username, time.Now()); wait > 0 {
+		return nil, fmt.Errorf("[session.SignIn] sign in is locked out: %w", &werrors.RetryAfterError{RetryAfter: wait})
+	}
+	wait, ok := s.limiter.delay(username, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("[session.SignIn] too many sign ins for the username: %w", &werrors.RetryAfterError{RetryAfter: wait})
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("[session.SignIn] gave up waiting to sign in: %w", ctx.Err())
+		}
 	}
-	return s.signInToGame(ctx, username, passcode)
+	res, err := s.signIn(ctx, username, passcode, client)
+	if err != nil {
+		if errors.Is(err, werrors.ErrUnauthorized) {
+			if wait := s.limiter.fail(client.IP, username, time.Now()); wait > 0 {
+				return nil, fmt.Errorf("[session.SignIn] too many failed sign ins: %w", &werrors.RetryAfterError{RetryAfter: wait})
+			}
+		}
+		return nil, err
+	}
+	s.limiter.succeed(client.IP, username)
+	return res, nil
 }
 
-func (s *Controller) signAdminToken() (*SignInResponse, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		Audience:  "rating-party",
-		ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
-		Issuer:    "rating-party",
-		IssuedAt:  time.Now().Unix(),
-		Subject:   "admin",
-	})
-	signedToken, err := token.SignedString([]byte(s.cfg.AdminJWTSecret))
-	if err != nil {
-		return nil, fmt.Errorf("[session.signAdminToken] failed to sign token: %w", err)
+func (s *Controller) signIn(ctx context.Context, username, passcode string, client Client) (*SignInResponse, error) {
+	if username == "admin" && subtle.ConstantTimeCompare([]byte(passcode), []byte(s.cfg.AdminPasscode)) == 1 {
+		return s.signAdminToken(ctx, client)
+	}
+	return s.signInToGame(ctx, username, passcode, client)
+}
+
+// GetLockouts lists the client addresses, and usernames at an address, that are currently locked out of signing in,
+// along with the usernames whose sign ins are being slowed down
+func (s *Controller) GetLockouts() []*Lockout {
+	return s.limiter.lockouts(time.Now())
+}
+
+// ClearLockout lets a locked out client address, or a username at an address, sign in again straight away
+func (s *Controller) ClearLockout(kind, value string) error {
+	if kind != LockoutKindIP && kind != LockoutKindUsername && kind != LockoutKindUsernameDelay {
+		return fmt.Errorf("[session.ClearLockout] unknown lockout kind %q: %w", kind, werrors.ErrBadRequest)
+	}
+	if !s.limiter.unlock(kind, value) {
+		return fmt.Errorf("[session.ClearLockout] no lockout found: %w", werrors.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Controller) signAdminToken(ctx context.Context, client Client) (*SignInResponse, error) {
+	var res *SignInResponse
+	if err := s.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		res, err = s.issueTokensTx(ctx, tx, "", "", client)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("[session.signAdminToken] failed to issue tokens: %w", err)
 	}
-	isAdmin := true
-	return &SignInResponse{
-		JWT:     signedToken,
-		IsAdmin: &isAdmin,
-	}, nil
+	return res, nil
 }
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/jacobtie/rating-party/server/internal/platform/jwtkeys"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
+)
+
+const refreshTokenBytes = 32
+
+// issueTokensTx starts a session for a participant, or for the admin when participantID is empty,
+// and returns its first access and refresh tokens
+func (s *Controller) issueTokensTx(ctx context.Context, tx *sqlx.Tx, participantID, gameID string, client Client) (*SignInResponse, error) {
+	expiresAt := time.Now().UTC().Add(s.refreshTokenTTL(participantID == "")).Truncate(time.Second)
+	sessionID, err := createSession(ctx, tx, participantID, gameID, client, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("[session.issueTokensTx] failed to create session: %w", err)
+	}
+	res, err := s.signTokensTx(ctx, tx, sessionID, participantID, gameID, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("[session.issueTokensTx] failed to sign tokens: %w", err)
+	}
+	return res, nil
+}
+
+// Refresh swaps a refresh token for a new access token and a new refresh token. Each refresh token can only
+// be used once, so a refresh token that is presented again has been stolen and the whole session is revoked.
+func (s *Controller) Refresh(ctx context.Context, refreshToken string) (*SignInResponse, error) {
+	ctx, span := tracing.Start(ctx, "session.Refresh")
+	defer span.End()
+	tokenHash := hashRefreshToken(refreshToken)
+	row := s.db.DB.QueryRowxContext(ctx, `
+		SELECT
+			refresh_token.session_id,
+			refresh_token.expires_at,
+			user_session.participant_id,
+			user_session.game_id,
+			user_session.revoked_at IS NOT NULL,
+			participant.deleted_at IS NOT NULL
+		FROM
+			refresh_token
+			JOIN user_session ON user_session.session_id = refresh_token.session_id
+			LEFT JOIN participant ON participant.participant_id = user_session.participant_id
+		WHERE refresh_token.token_hash = $1
+		;
+	`, tokenHash)
+	var sessionID string
+	var expiresAt time.Time
+	var participantID, gameID sql.NullString
+	var isRevoked bool
+	var isParticipantDeleted sql.NullBool
+	if err := row.Scan(&sessionID, &expiresAt, &participantID, &gameID, &isRevoked, &isParticipantDeleted); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("[session.Refresh] unknown refresh token: %w", werrors.ErrUnauthorized)
+		}
+		return nil, fmt.Errorf("[session.Refresh] failed to scan row: %w", err)
+	}
+	if isRevoked {
+		return nil, fmt.Errorf("[session.Refresh] session has been revoked: %w", werrors.ErrUnauthorized)
+	}
+	if !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("[session.Refresh] refresh token is expired: %w", werrors.ErrUnauthorized)
+	}
+	if isParticipantDeleted.Bool {
+		return nil, fmt.Errorf("[session.Refresh] participant has been deleted: %w", werrors.ErrUnauthorized)
+	}
+	// Marking the token used only succeeds once, even when two refreshes race. It happens in the same transaction
+	// as issuing the new tokens, so a failed refresh leaves the old token usable for a retry.
+	var res *SignInResponse
+	reused := false
+	if err := s.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE refresh_token SET used_at = NOW() WHERE token_hash = $1 AND used_at IS NULL;
+		`, tokenHash)
+		if err != nil {
+			return fmt.Errorf("[session.Refresh] failed to use refresh token: %w", err)
+		}
+		used, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("[session.Refresh] failed to get updated rows: %w", err)
+		}
+		if used == 0 {
+			reused = true
+			return fmt.Errorf("[session.Refresh] refresh token was already used: %w", werrors.ErrUnauthorized)
+		}
+		sessionExpiresAt := time.Now().UTC().Add(s.refreshTokenTTL(!participantID.Valid)).Truncate(time.Second)
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE user_session SET expires_at = $1 WHERE session_id = $2;
+		`, sessionExpiresAt, sessionID); err != nil {
+			return fmt.Errorf("[session.Refresh] failed to extend session: %w", err)
+		}
+		res, err = s.signTokensTx(ctx, tx, sessionID, participantID.String, gameID.String, sessionExpiresAt)
+		return err
+	}); err != nil {
+		if reused {
+			if _, err := s.revokeSession(ctx, sessionID); err != nil {
+				return nil, fmt.Errorf("[session.Refresh] failed to revoke session after refresh token reuse: %w", err)
+			}
+			return nil, fmt.Errorf("[session.Refresh] refresh token was reused, session revoked: %w", werrors.ErrUnauthorized)
+		}
+		return nil, fmt.Errorf("[session.Refresh] failed to refresh tokens: %w", err)
+	}
+	return res, nil
+}
+
+func (s *Controller) refreshTokenTTL(isAdmin bool) time.Duration {
+	if isAdmin {
+		return s.cfg.AdminRefreshTokenTTL
+	}
+	return s.cfg.RefreshTokenTTL
+}
+
+// signTokensTx signs an access token for the session and stores a new refresh token that is valid until the session expires
+func (s *Controller) signTokensTx(ctx context.Context, tx *sqlx.Tx, sessionID, participantID, gameID string, sessionExpiresAt time.Time) (*SignInResponse, error) {
+	accessToken, err := s.signAccessToken(sessionID, participantID, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[session.signTokensTx] failed to sign access token: %w", err)
+	}
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("[session.signTokensTx] failed to generate refresh token: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_token (token_hash, session_id, expires_at) VALUES ($1, $2, $3);
+	`, hashRefreshToken(refreshToken), sessionID, sessionExpiresAt); err != nil {
+		return nil, fmt.Errorf("[session.signTokensTx] failed to store refresh token: %w", err)
+	}
+	res := &SignInResponse{
+		JWT:          accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.cfg.AccessTokenTTL.Seconds()),
+	}
+	if participantID == "" {
+		isAdmin := true
+		res.IsAdmin = &isAdmin
+	} else {
+		res.GameID = &gameID
+	}
+	return res, nil
+}
+
+// signAccessToken signs a token for the admin when participantID is empty, otherwise for the participant in the game.
+// The jti is the session ID so revoking the session revokes every access token issued for it.
+func (s *Controller) signAccessToken(sessionID, participantID, gameID string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"aud": "rating-party",
+		"exp": now.Add(s.cfg.AccessTokenTTL).Unix(),
+		"jti": sessionID,
+		"iss": "rating-party",
+		"iat": now.Unix(),
+		"sub": "admin",
+	}
+	if participantID != "" {
+		claims["sub"] = participantID
+		claims["gameId"] = gameID
+	}
+	keys, err := jwtkeys.Get()
+	if err != nil {
+		return "", fmt.Errorf("[session.signAccessToken] failed to get signing keys: %w", err)
+	}
+	signedToken, err := keys.Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("[session.signAccessToken] failed to sign token: %w", err)
+	}
+	return signedToken, nil
+}
+
+func newRefreshToken() (string, error) {
+	token := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// hashRefreshToken is what gets stored, so a leaked database cannot be used to refresh sessions
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,30 @@
+package template
+
+// Template is a reusable game setup. The scorecard is the same for every game, so only the ranking settings,
+// the join settings and optionally the wine list are kept. The code expiry is a fixed point in time, so it is
+// left out and new games start with a code that never expires.
+type Template struct {
+	TemplateID             string          `json:"templateId"`
+	TemplateName           string          `json:"templateName"`
+	RankingStrategy        string          `json:"rankingStrategy"`
+	TieBreakers            []string        `json:"tieBreakers"`
+	MergeDuplicates        bool            `json:"mergeDuplicates"`
+	CodeDisabled           bool            `json:"codeDisabled"`
+	DisableCodeWhenStopped bool            `json:"disableCodeWhenStopped"`
+	MaxJoins               int             `json:"maxJoins"`
+	Wines                  []*TemplateWine `json:"wines"`
+}
+
+type TemplateWine struct {
+	TemplateWineID string `json:"templateWineId"`
+	WineName       string `json:"wineName"`
+	WineCode       string `json:"wineCode"`
+	WineYear       int    `json:"wineYear"`
+	DuplicateOf    string `json:"duplicateOf,omitempty"`
+}
+
+// CopyOptions controls how much of a game's wine list is copied into a new game or template
+type CopyOptions struct {
+	IncludeWines     bool `json:"includeWines"`
+	IncludeWineNames bool `json:"includeWineNames"`
+}
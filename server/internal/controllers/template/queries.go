@@ -0,0 +1,308 @@
+package template
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
+)
+
+func (c *Controller) GetAllTemplates(ctx context.Context) ([]*Template, error) {
+	ctx, span := tracing.Start(ctx, "template.GetAllTemplates")
+	defer span.End()
+	rows, err := c.db.DB.QueryxContext(ctx, `
+		SELECT
+			template_id,
+			template_name,
+			ranking_strategy,
+			tie_breakers,
+			merge_duplicates,
+			code_disabled,
+			disable_code_when_stopped,
+			max_joins
+		FROM
+			game_template
+		ORDER BY template_name ASC
+		;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("[template.GetAllTemplates] failed to query templates: %w", err)
+	}
+	defer rows.Close()
+	templates := make([]*Template, 0)
+	byID := make(map[string]*Template)
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("[template.GetAllTemplates] failed to scan row: %w", err)
+		}
+		templates = append(templates, t)
+		byID[t.TemplateID] = t
+	}
+	wines, err := c.getTemplateWines(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("[template.GetAllTemplates] failed to get wines: %w", err)
+	}
+	for templateID, templateWines := range wines {
+		if t, ok := byID[templateID]; ok {
+			t.Wines = templateWines
+		}
+	}
+	return templates, nil
+}
+
+func (c *Controller) GetTemplate(ctx context.Context, templateID string) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "template.GetTemplate")
+	defer span.End()
+	row := c.db.DB.QueryRowxContext(ctx, `
+		SELECT
+			template_id,
+			template_name,
+			ranking_strategy,
+			tie_breakers,
+			merge_duplicates,
+			code_disabled,
+			disable_code_when_stopped,
+			max_joins
+		FROM
+			game_template
+		WHERE template_id = $1
+		;
+	`, templateID)
+	t, err := scanTemplate(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("[template.GetTemplate] no template found: %w", werrors.ErrNotFound)
+		}
+		return nil, fmt.Errorf("[template.GetTemplate] failed to scan row: %w", err)
+	}
+	wines, err := c.getTemplateWines(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("[template.GetTemplate] failed to get wines: %w", err)
+	}
+	if templateWines, ok := wines[templateID]; ok {
+		t.Wines = templateWines
+	}
+	return t, nil
+}
+
+func scanTemplate(row interface{ Scan(...any) error }) (*Template, error) {
+	t := Template{Wines: make([]*TemplateWine, 0)}
+	var tieBreakers string
+	if err := row.Scan(
+		&t.TemplateID,
+		&t.TemplateName,
+		&t.RankingStrategy,
+		&tieBreakers,
+		&t.MergeDuplicates,
+		&t.CodeDisabled,
+		&t.DisableCodeWhenStopped,
+		&t.MaxJoins,
+	); err != nil {
+		return nil, err
+	}
+	t.TieBreakers = []string{}
+	if tieBreakers != "" {
+		t.TieBreakers = strings.Split(tieBreakers, ",")
+	}
+	return &t, nil
+}
+
+// getTemplateWines returns the wines of one template, or of every template when templateID is empty, keyed by template ID
+func (c *Controller) getTemplateWines(ctx context.Context, templateID string) (map[string][]*TemplateWine, error) {
+	query := `
+		SELECT
+			template_id,
+			template_wine_id,
+			wine_name,
+			wine_code,
+			wine_year,
+			duplicate_of
+		FROM
+			game_template_wine
+	`
+	args := make([]any, 0, 1)
+	if templateID != "" {
+		query += `WHERE template_id = $1`
+		args = append(args, templateID)
+	}
+	rows, err := c.db.DB.QueryxContext(ctx, query+` ORDER BY wine_code ASC;`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("[template.getTemplateWines] failed to query wines: %w", err)
+	}
+	defer rows.Close()
+	wines := make(map[string][]*TemplateWine)
+	for rows.Next() {
+		var id string
+		var w TemplateWine
+		var duplicateOf sql.NullString
+		if err := rows.Scan(&id, &w.TemplateWineID, &w.WineName, &w.WineCode, &w.WineYear, &duplicateOf); err != nil {
+			return nil, fmt.Errorf("[template.getTemplateWines] failed to scan row: %w", err)
+		}
+		w.DuplicateOf = duplicateOf.String
+		wines[id] = append(wines[id], &w)
+	}
+	return wines, nil
+}
+
+// SaveTemplate saves the game's settings and optionally its wine list as a template
+func (c *Controller) SaveTemplate(ctx context.Context, gameID, templateName string, opts CopyOptions) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "template.SaveTemplate")
+	defer span.End()
+	t, err := c.snapshot(ctx, gameID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[template.SaveTemplate] failed to copy game: %w", err)
+	}
+	if templateName != "" {
+		t.TemplateName = templateName
+	}
+	templateID := uuid.New().String()
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO game_template (
+				template_id,
+				template_name,
+				ranking_strategy,
+				tie_breakers,
+				merge_duplicates,
+				code_disabled,
+				disable_code_when_stopped,
+				max_joins
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+		`, templateID, t.TemplateName, t.RankingStrategy, strings.Join(t.TieBreakers, ","), t.MergeDuplicates, t.CodeDisabled, t.DisableCodeWhenStopped, t.MaxJoins); err != nil {
+			return fmt.Errorf("[template.SaveTemplate] failed to create template: %w", err)
+		}
+		for _, w := range t.Wines {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO game_template_wine (template_wine_id, template_id, wine_name, wine_code, wine_year) VALUES ($1, $2, $3, $4, $5);
+			`, w.TemplateWineID, templateID, w.WineName, w.WineCode, w.WineYear); err != nil {
+				return fmt.Errorf("[template.SaveTemplate] failed to create template wine: %w", err)
+			}
+		}
+		for _, w := range t.Wines {
+			if w.DuplicateOf == "" {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE game_template_wine SET duplicate_of = $1 WHERE template_wine_id = $2;
+			`, w.DuplicateOf, w.TemplateWineID); err != nil {
+				return fmt.Errorf("[template.SaveTemplate] failed to mark duplicate wine: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[template.SaveTemplate] failed to save template: %w", err)
+	}
+	saved, err := c.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("[template.SaveTemplate] failed to get saved template: %w", err)
+	}
+	return saved, nil
+}
+
+// CreateGameFromTemplate starts a new game with the template's settings and wine list
+func (c *Controller) CreateGameFromTemplate(ctx context.Context, templateID, gameName string) (*game.Game, error) {
+	ctx, span := tracing.Start(ctx, "template.CreateGameFromTemplate")
+	defer span.End()
+	t, err := c.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("[template.CreateGameFromTemplate] failed to get template: %w", err)
+	}
+	if gameName == "" {
+		gameName = t.TemplateName
+	}
+	created, err := c.createGame(ctx, t, gameName)
+	if err != nil {
+		return nil, fmt.Errorf("[template.CreateGameFromTemplate] failed to create game: %w", err)
+	}
+	return created, nil
+}
+
+func (c *Controller) DeleteTemplate(ctx context.Context, templateID string) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "template.DeleteTemplate")
+	defer span.End()
+	t, err := c.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("[template.DeleteTemplate] failed to get template: %w", err)
+	}
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM game_template_wine WHERE template_id = $1 AND duplicate_of IS NOT NULL;
+		`, templateID); err != nil {
+			return fmt.Errorf("[template.DeleteTemplate] failed to delete duplicate wines: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM game_template_wine WHERE template_id = $1;
+		`, templateID); err != nil {
+			return fmt.Errorf("[template.DeleteTemplate] failed to delete wines: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM game_template WHERE template_id = $1;
+		`, templateID); err != nil {
+			return fmt.Errorf("[template.DeleteTemplate] failed to delete template: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[template.DeleteTemplate] failed to delete template: %w", err)
+	}
+	return t, nil
+}
+
+// createGame inserts a new game that is not yet running, with a fresh game code and the template's wines
+func (c *Controller) createGame(ctx context.Context, t *Template, gameName string) (*game.Game, error) {
+	gameID := uuid.New().String()
+	wines := gameWines(t)
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		gameCode, err := c.gameController.GenerateGameCode(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("[template.createGame] failed to generate game code: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO game (
+				game_id,
+				game_name,
+				game_code,
+				ranking_strategy,
+				tie_breakers,
+				merge_duplicates,
+				code_disabled,
+				disable_code_when_stopped,
+				max_joins
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+		`, gameID, gameName, gameCode, t.RankingStrategy, strings.Join(t.TieBreakers, ","), t.MergeDuplicates, t.CodeDisabled, t.DisableCodeWhenStopped, t.MaxJoins); err != nil {
+			return fmt.Errorf("[template.createGame] failed to create game: %w", err)
+		}
+		for _, w := range wines {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO wine (wine_id, game_id, wine_name, wine_code, wine_year) VALUES ($1, $2, $3, $4, $5);
+			`, w.WineID, gameID, w.WineName, w.WineCode, w.WineYear); err != nil {
+				return fmt.Errorf("[template.createGame] failed to create wine: %w", err)
+			}
+		}
+		for _, w := range wines {
+			if w.DuplicateOfWineID == "" {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE wine SET duplicate_of = $1 WHERE wine_id = $2;
+			`, w.DuplicateOfWineID, w.WineID); err != nil {
+				return fmt.Errorf("[template.createGame] failed to mark duplicate wine: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[template.createGame] failed to create game: %w", err)
+	}
+	created, err := c.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[template.createGame] failed to get created game: %w", err)
+	}
+	return created, nil
+}
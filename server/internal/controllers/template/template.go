@@ -0,0 +1,115 @@
+package template
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+)
+
+type Controller struct {
+	cfg            *config.Config
+	db             *db.DB
+	gameController *game.Controller
+	wineController *wine.Controller
+}
+
+func NewController(cfg *config.Config, db *db.DB, gameController *game.Controller, wineController *wine.Controller) *Controller {
+	return &Controller{
+		cfg:            cfg,
+		db:             db,
+		gameController: gameController,
+		wineController: wineController,
+	}
+}
+
+// CloneGame copies the game's settings and optionally its wine list into a new game with a fresh game code
+func (c *Controller) CloneGame(ctx context.Context, gameID, gameName string, opts CopyOptions) (*game.Game, error) {
+	ctx, span := tracing.Start(ctx, "template.CloneGame")
+	defer span.End()
+	t, err := c.snapshot(ctx, gameID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[template.CloneGame] failed to copy game: %w", err)
+	}
+	if gameName == "" {
+		gameName = fmt.Sprintf("%s (copy)", t.TemplateName)
+	}
+	created, err := c.createGame(ctx, t, gameName)
+	if err != nil {
+		return nil, fmt.Errorf("[template.CloneGame] failed to create game: %w", err)
+	}
+	return created, nil
+}
+
+// snapshot builds an unsaved template from the game, with fresh template wine IDs
+func (c *Controller) snapshot(ctx context.Context, gameID string, opts CopyOptions) (*Template, error) {
+	g, err := c.gameController.GetSingle(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("[template.snapshot] failed to get game: %w", err)
+	}
+	wines := make([]*wine.Wine, 0)
+	if opts.IncludeWines {
+		if wines, err = c.wineController.GetAllWines(ctx, gameID); err != nil {
+			return nil, fmt.Errorf("[template.snapshot] failed to get wines: %w", err)
+		}
+	}
+	return newTemplate(g, wines, opts), nil
+}
+
+// newTemplate copies the game's settings and, if opts include them, its wines into a template
+func newTemplate(g *game.Game, wines []*wine.Wine, opts CopyOptions) *Template {
+	t := &Template{
+		TemplateName:           g.GameName,
+		RankingStrategy:        g.RankingStrategy,
+		TieBreakers:            g.TieBreakers,
+		MergeDuplicates:        g.MergeDuplicates,
+		CodeDisabled:           g.CodeDisabled,
+		DisableCodeWhenStopped: g.DisableCodeWhenStopped,
+		MaxJoins:               g.MaxJoins,
+		Wines:                  make([]*TemplateWine, 0),
+	}
+	if !opts.IncludeWines {
+		return t
+	}
+	templateWineIDs := make(map[string]string, len(wines))
+	for _, w := range wines {
+		templateWineIDs[w.WineID] = uuid.New().String()
+	}
+	for _, w := range wines {
+		templateWine := &TemplateWine{
+			TemplateWineID: templateWineIDs[w.WineID],
+			WineCode:       w.WineCode,
+			WineYear:       w.WineYear,
+			DuplicateOf:    templateWineIDs[w.DuplicateOfWineID],
+		}
+		if opts.IncludeWineNames {
+			templateWine.WineName = w.WineName
+		}
+		t.Wines = append(t.Wines, templateWine)
+	}
+	return t
+}
+
+// gameWines returns the template's wines with fresh wine IDs, ready to be added to a new game
+func gameWines(t *Template) []*wine.Wine {
+	wineIDs := make(map[string]string, len(t.Wines))
+	for _, w := range t.Wines {
+		wineIDs[w.TemplateWineID] = uuid.New().String()
+	}
+	wines := make([]*wine.Wine, 0, len(t.Wines))
+	for _, w := range t.Wines {
+		wines = append(wines, &wine.Wine{
+			WineID:            wineIDs[w.TemplateWineID],
+			WineName:          w.WineName,
+			WineCode:          w.WineCode,
+			WineYear:          w.WineYear,
+			DuplicateOfWineID: wineIDs[w.DuplicateOf],
+		})
+	}
+	return wines
+}
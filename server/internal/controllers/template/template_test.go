@@ -0,0 +1,127 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
+)
+
+func newTestGame() (*game.Game, []*wine.Wine) {
+	expiresAt := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	g := &game.Game{
+		GameID:          "game",
+		GameName:        "Spring tasting",
+		RankingStrategy: "bayesian",
+		TieBreakers:     []string{"taste", "shared"},
+		MergeDuplicates: true,
+		JoinSettings: game.JoinSettings{
+			CodeDisabled:           true,
+			DisableCodeWhenStopped: true,
+			CodeExpiresAt:          &expiresAt,
+			MaxJoins:               12,
+		},
+		JoinCount: 4,
+	}
+	wines := []*wine.Wine{
+		{WineID: "red", WineName: "Red", WineCode: "A", WineYear: 2019},
+		{WineID: "red-again", WineName: "Red", WineCode: "B", WineYear: 2019, DuplicateOfWineID: "red"},
+		{WineID: "white", WineName: "White", WineCode: "C", WineYear: 2021},
+	}
+	return g, wines
+}
+
+func TestCopyGame(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  CopyOptions
+		wines int
+		names bool
+	}{
+		{name: "settings only", opts: CopyOptions{}},
+		{name: "names without wines are ignored", opts: CopyOptions{IncludeWineNames: true}},
+		{name: "wines without names", opts: CopyOptions{IncludeWines: true}, wines: 3},
+		{name: "wines with names", opts: CopyOptions{IncludeWines: true, IncludeWineNames: true}, wines: 3, names: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, wines := newTestGame()
+			tmpl := newTemplate(g, wines, tt.opts)
+			if tmpl.TemplateName != g.GameName || tmpl.RankingStrategy != g.RankingStrategy || !reflect.DeepEqual(tmpl.TieBreakers, g.TieBreakers) || !tmpl.MergeDuplicates {
+				t.Fatalf("ranking settings were not copied: %+v", tmpl)
+			}
+			if !tmpl.CodeDisabled || !tmpl.DisableCodeWhenStopped || tmpl.MaxJoins != 12 {
+				t.Fatalf("join settings were not copied: %+v", tmpl)
+			}
+			if len(tmpl.Wines) != tt.wines {
+				t.Fatalf("template has %d wines, want %d", len(tmpl.Wines), tt.wines)
+			}
+
+			templateCodes := make(map[string]string)
+			for i, w := range tmpl.Wines {
+				original := wines[i]
+				if w.TemplateWineID == "" || w.TemplateWineID == original.WineID {
+					t.Fatalf("template wine %s kept the game's wine ID", w.WineCode)
+				}
+				if w.WineCode != original.WineCode || w.WineYear != original.WineYear {
+					t.Fatalf("template wine %s was not copied", w.WineCode)
+				}
+				if (w.WineName != "") != tt.names {
+					t.Fatalf("template wine %s has name %q, want names copied %v", w.WineCode, w.WineName, tt.names)
+				}
+				templateCodes[w.TemplateWineID] = w.WineCode
+			}
+			if tt.wines > 0 {
+				if original := templateCodes[tmpl.Wines[1].DuplicateOf]; original != "A" {
+					t.Fatalf("template duplicate points at wine %q, want A", original)
+				}
+				if tmpl.Wines[0].DuplicateOf != "" || tmpl.Wines[2].DuplicateOf != "" {
+					t.Fatalf("wines that are not duplicates were marked as duplicates")
+				}
+			}
+
+			// Creating a game from the template gives every wine another fresh ID
+			created := gameWines(tmpl)
+			if len(created) != tt.wines {
+				t.Fatalf("game has %d wines, want %d", len(created), tt.wines)
+			}
+			gameCodes := make(map[string]string)
+			for i, w := range created {
+				if w.WineID == "" || w.WineID == tmpl.Wines[i].TemplateWineID || w.WineID == wines[i].WineID {
+					t.Fatalf("game wine %s reused an ID", w.WineCode)
+				}
+				if w.WineCode != tmpl.Wines[i].WineCode || w.WineName != tmpl.Wines[i].WineName || w.WineYear != tmpl.Wines[i].WineYear {
+					t.Fatalf("game wine %s was not copied from the template", w.WineCode)
+				}
+				gameCodes[w.WineID] = w.WineCode
+			}
+			if tt.wines > 0 {
+				if original := gameCodes[created[1].DuplicateOfWineID]; original != "A" {
+					t.Fatalf("game duplicate points at wine %q, want A", original)
+				}
+				if created[0].DuplicateOfWineID != "" || created[2].DuplicateOfWineID != "" {
+					t.Fatalf("wines that are not duplicates were marked as duplicates")
+				}
+			}
+		})
+	}
+}
+
+func TestGameWinesFromSavedTemplate(t *testing.T) {
+	// A template saved earlier may list a duplicate before the wine it duplicates
+	tmpl := &Template{
+		Wines: []*TemplateWine{
+			{TemplateWineID: "second", WineCode: "B", DuplicateOf: "first"},
+			{TemplateWineID: "first", WineCode: "A"},
+		},
+	}
+	created := gameWines(tmpl)
+	if created[0].DuplicateOfWineID != created[1].WineID {
+		t.Fatalf("duplicate points at %q, want %q", created[0].DuplicateOfWineID, created[1].WineID)
+	}
+	if created[0].WineID == created[1].WineID {
+		t.Fatalf("wines were given the same ID")
+	}
+}
@@ -1,8 +1,12 @@
 package wine
 
+import "time"
+
 type Wine struct {
-	WineID   string `json:"wineId"`
-	WineName string `json:"wineName,omitempty"`
-	WineCode string `json:"wineCode"`
-	WineYear int    `json:"wineYear,omitempty"`
+	WineID            string     `json:"wineId"`
+	WineName          string     `json:"wineName,omitempty"`
+	WineCode          string     `json:"wineCode"`
+	WineYear          int        `json:"wineYear,omitempty"`
+	DuplicateOfWineID string     `json:"duplicateOfWineId,omitempty"`
+	DeletedAt         *time.Time `json:"deletedAt,omitempty"`
 }
@@ -5,14 +5,19 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
 	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/jmoiron/sqlx"
 )
 
 func (c *Controller) GetAllWines(ctx context.Context, gameID string) ([]*Wine, error) {
+	ctx, span := tracing.Start(ctx, "wine.GetAllWines")
+	defer span.End()
 	rows, err := c.db.QueryxContext(ctx, `
-		SELECT wine_id, wine_name, wine_code, wine_year FROM wine WHERE game_id = $1 ORDER BY wine_code ASC
+		SELECT wine_id, wine_name, wine_code, wine_year, duplicate_of FROM wine WHERE game_id = $1 AND deleted_at IS NULL ORDER BY wine_code ASC
 	`, gameID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -24,39 +29,49 @@ func (c *Controller) GetAllWines(ctx context.Context, gameID string) ([]*Wine, e
 	wines := make([]*Wine, 0)
 	for rows.Next() {
 		var wine Wine
+		var duplicateOf sql.NullString
 		if err := rows.Scan(
 			&wine.WineID,
 			&wine.WineName,
 			&wine.WineCode,
 			&wine.WineYear,
+			&duplicateOf,
 		); err != nil {
 			return nil, fmt.Errorf("[wine.GetAllWines] failed to scan row: %w", err)
 		}
+		wine.DuplicateOfWineID = duplicateOf.String
 		wines = append(wines, &wine)
 	}
 	return wines, nil
 }
 
 func (c *Controller) GetSingleWine(ctx context.Context, wineID string) (*Wine, error) {
+	ctx, span := tracing.Start(ctx, "wine.GetSingleWine")
+	defer span.End()
 	row := c.db.QueryRowxContext(ctx, `
-		SELECT wine_id, wine_name, wine_code, wine_year FROM wine WHERE wine_id = $1
+		SELECT wine_id, wine_name, wine_code, wine_year, duplicate_of FROM wine WHERE wine_id = $1 AND deleted_at IS NULL
 	`, wineID)
 	var wine Wine
+	var duplicateOf sql.NullString
 	if err := row.Scan(
 		&wine.WineID,
 		&wine.WineName,
 		&wine.WineCode,
 		&wine.WineYear,
+		&duplicateOf,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("[wine.GetSingleWine] no wine found: %w", werrors.ErrNotFound)
 		}
 		return nil, fmt.Errorf("[wine.GetSingleWine] failed to scan row: %w", err)
 	}
+	wine.DuplicateOfWineID = duplicateOf.String
 	return &wine, nil
 }
 
 func (c *Controller) CreateWine(ctx context.Context, gameID, wineName, wineCode string, wineYear int) (*Wine, error) {
+	ctx, span := tracing.Start(ctx, "wine.CreateWine")
+	defer span.End()
 	wineID := uuid.New().String()
 	if _, err := c.db.ExecContext(ctx, `
 		INSERT INTO wine (wine_id, game_id, wine_name, wine_code, wine_year) VALUES ($1, $2, $3, $4, $5)
@@ -71,6 +86,8 @@ func (c *Controller) CreateWine(ctx context.Context, gameID, wineName, wineCode
 }
 
 func (c *Controller) UpdateWine(ctx context.Context, wineID, wineName, wineCode string, wineYear int) error {
+	ctx, span := tracing.Start(ctx, "wine.UpdateWine")
+	defer span.End()
 	if _, err := c.GetSingleWine(ctx, wineID); err != nil {
 		return fmt.Errorf("[wine.UpdateWine] failed to get wine: %w", err)
 	}
@@ -82,15 +99,180 @@ func (c *Controller) UpdateWine(ctx context.Context, wineID, wineName, wineCode
 	return nil
 }
 
+// SetDuplicateOf marks the wine as a second pour of another wine in the same game, or clears the mark when duplicateOfWineID is empty
+func (c *Controller) SetDuplicateOf(ctx context.Context, gameID, wineID, duplicateOfWineID string) error {
+	ctx, span := tracing.Start(ctx, "wine.SetDuplicateOf")
+	defer span.End()
+	wines, err := c.GetAllWines(ctx, gameID)
+	if err != nil {
+		return fmt.Errorf("[wine.SetDuplicateOf] failed to get wines: %w", err)
+	}
+	winesByID := make(map[string]*Wine, len(wines))
+	for _, wine := range wines {
+		winesByID[wine.WineID] = wine
+	}
+	if _, ok := winesByID[wineID]; !ok {
+		return fmt.Errorf("[wine.SetDuplicateOf] no wine found in game: %w", werrors.ErrNotFound)
+	}
+	var duplicateOf sql.NullString
+	if duplicateOfWineID != "" {
+		original, ok := winesByID[duplicateOfWineID]
+		if !ok {
+			return fmt.Errorf("[wine.SetDuplicateOf] no original wine found in game: %w", werrors.ErrBadRequest)
+		}
+		if duplicateOfWineID == wineID {
+			return fmt.Errorf("[wine.SetDuplicateOf] wine cannot be a duplicate of itself: %w", werrors.ErrBadRequest)
+		}
+		if original.DuplicateOfWineID != "" {
+			return fmt.Errorf("[wine.SetDuplicateOf] original wine is itself a duplicate: %w", werrors.ErrBadRequest)
+		}
+		for _, wine := range wines {
+			if wine.DuplicateOfWineID == wineID {
+				return fmt.Errorf("[wine.SetDuplicateOf] wine already has duplicates: %w", werrors.ErrBadRequest)
+			}
+		}
+		duplicateOf = sql.NullString{String: duplicateOfWineID, Valid: true}
+	}
+	if _, err := c.db.ExecContext(ctx, `
+		UPDATE wine SET duplicate_of = $1 WHERE wine_id = $2
+	`, duplicateOf, wineID); err != nil {
+		return fmt.Errorf("[wine.SetDuplicateOf] failed to update wine: %w", err)
+	}
+	return nil
+}
+
+// DeleteWine soft deletes the wine along with its ratings so it can be restored until the retention window has passed.
+// Wines marked as its duplicates are unmarked, and stay unmarked if it is restored.
 func (c *Controller) DeleteWine(ctx context.Context, wineID string) (*Wine, error) {
+	ctx, span := tracing.Start(ctx, "wine.DeleteWine")
+	defer span.End()
 	wine, err := c.GetSingleWine(ctx, wineID)
 	if err != nil {
 		return nil, fmt.Errorf("[wine.DeleteWine] failed to get wine: %w", err)
 	}
-	if _, err := c.db.ExecContext(ctx, `
-		DELETE FROM wine WHERE wine_id = $1
-	`, wineID); err != nil {
+	deletedAt := time.Now().UTC()
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE wine SET duplicate_of = NULL WHERE duplicate_of = $1
+		`, wineID); err != nil {
+			return fmt.Errorf("[wine.DeleteWine] failed to clear duplicates: %w", err)
+		}
+		for _, table := range wineTables {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+				UPDATE %s SET deleted_at = $1 WHERE wine_id = $2 AND deleted_at IS NULL
+			`, table), deletedAt, wineID); err != nil {
+				return fmt.Errorf("[wine.DeleteWine] failed to delete from %s: %w", table, err)
+			}
+		}
+		return nil
+	}); err != nil {
 		return nil, fmt.Errorf("[wine.DeleteWine] failed to delete wine: %w", err)
 	}
+	wine.DeletedAt = &deletedAt
+	return wine, nil
+}
+
+// wineTables are the tables holding a wine and its ratings, children first
+var wineTables = []string{"rating", "wine"}
+
+// GetAllDeletedWines lists the wines deleted from an active game that are still within the retention window.
+// Wines deleted along with their game come back when the game is restored instead.
+func (c *Controller) GetAllDeletedWines(ctx context.Context, gameID string) ([]*Wine, error) {
+	ctx, span := tracing.Start(ctx, "wine.GetAllDeletedWines")
+	defer span.End()
+	rows, err := c.db.QueryxContext(ctx, `
+		SELECT wine_id, wine_name, wine_code, wine_year, wine.deleted_at
+		FROM wine
+		JOIN game ON game.game_id = wine.game_id
+		WHERE wine.game_id = $1 AND wine.deleted_at IS NOT NULL AND wine.deleted_at > $2 AND game.deleted_at IS NULL
+		ORDER BY wine.deleted_at DESC
+	`, gameID, time.Now().UTC().Add(-c.cfg.GameRetention))
+	if err != nil {
+		return nil, fmt.Errorf("[wine.GetAllDeletedWines] failed to query: %w", err)
+	}
+	defer rows.Close()
+	wines := make([]*Wine, 0)
+	for rows.Next() {
+		var wine Wine
+		if err := rows.Scan(
+			&wine.WineID,
+			&wine.WineName,
+			&wine.WineCode,
+			&wine.WineYear,
+			&wine.DeletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("[wine.GetAllDeletedWines] failed to scan row: %w", err)
+		}
+		wines = append(wines, &wine)
+	}
+	return wines, nil
+}
+
+// RestoreWine brings back a soft deleted wine along with the ratings deleted with it
+func (c *Controller) RestoreWine(ctx context.Context, gameID, wineID string) (*Wine, error) {
+	ctx, span := tracing.Start(ctx, "wine.RestoreWine")
+	defer span.End()
+	row := c.db.QueryRowxContext(ctx, `
+		SELECT wine.deleted_at
+		FROM wine
+		JOIN game ON game.game_id = wine.game_id
+		WHERE wine.wine_id = $1 AND wine.game_id = $2 AND wine.deleted_at IS NOT NULL AND game.deleted_at IS NULL
+	`, wineID, gameID)
+	var deletedAt time.Time
+	if err := row.Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("[wine.RestoreWine] no deleted wine found: %w", werrors.ErrNotFound)
+		}
+		return nil, fmt.Errorf("[wine.RestoreWine] failed to scan row: %w", err)
+	}
+	if time.Since(deletedAt) > c.cfg.GameRetention {
+		return nil, fmt.Errorf("[wine.RestoreWine] wine was deleted more than %s ago: %w", c.cfg.GameRetention, werrors.ErrBadRequest)
+	}
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		for _, table := range wineTables {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+				UPDATE %s SET deleted_at = NULL WHERE wine_id = $1 AND deleted_at = $2
+			`, table), wineID, deletedAt); err != nil {
+				return fmt.Errorf("[wine.RestoreWine] failed to restore %s: %w", table, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[wine.RestoreWine] failed to restore wine: %w", err)
+	}
+	wine, err := c.GetSingleWine(ctx, wineID)
+	if err != nil {
+		return nil, fmt.Errorf("[wine.RestoreWine] failed to get restored wine: %w", err)
+	}
 	return wine, nil
 }
+
+// PurgeDeleted permanently removes wines soft deleted before the given time along with their ratings, and returns how many were purged
+func (c *Controller) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "wine.PurgeDeleted")
+	defer span.End()
+	var purged int64
+	if err := c.db.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE wine SET duplicate_of = NULL WHERE duplicate_of IN (SELECT wine_id FROM wine WHERE deleted_at < $1)
+		`, deletedBefore); err != nil {
+			return fmt.Errorf("[wine.PurgeDeleted] failed to clear duplicate wines: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM rating WHERE wine_id IN (SELECT wine_id FROM wine WHERE deleted_at < $1)
+		`, deletedBefore); err != nil {
+			return fmt.Errorf("[wine.PurgeDeleted] failed to delete ratings: %w", err)
+		}
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM wine WHERE deleted_at < $1
+		`, deletedBefore)
+		if err != nil {
+			return fmt.Errorf("[wine.PurgeDeleted] failed to delete wines: %w", err)
+		}
+		purged, err = result.RowsAffected()
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("[wine.PurgeDeleted] failed to purge wines: %w", err)
+	}
+	return int(purged), nil
+}
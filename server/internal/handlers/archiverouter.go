@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/archive"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/middleware"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/spreadsheet"
+	"github.com/jacobtie/rating-party/server/internal/platform/web"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxArchiveSize caps the size of an uploaded game archive
+const maxArchiveSize = 32 << 20
+
+type archiveRouter struct {
+	controller *archive.Controller
+}
+
+func registerArchiveRoutes(service *web.Service, cfg *config.Config, db *db.DB) {
+	router := &archiveRouter{
+		controller: archive.NewController(cfg, db, game.NewController(cfg, db)),
+	}
+	service.Handle(http.MethodGet, "/api/v1/games/:gameId/archive", router.exportArchive, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPost, "/api/v1/archives", router.importArchive, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+}
+
+func (a *archiveRouter) exportArchive(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.exportArchive] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.exportArchive] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.exportArchive] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	gameArchive, err := a.controller.Export(ctx, gameID)
+	if err != nil {
+		return fmt.Errorf("[handlers.exportArchive]: %w", err)
+	}
+	data, err := json.MarshalIndent(gameArchive, "", "  ")
+	if err != nil {
+		return fmt.Errorf("[handlers.exportArchive] failed to encode archive: %w", err)
+	}
+	web.RespondFile(ctx, w, data, "application/json", spreadsheet.Filename(gameArchive.Game.GameName, "archive", "json"), http.StatusOK)
+	return nil
+}
+
+func (a *archiveRouter) importArchive(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	var gameArchive archive.Archive
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxArchiveSize)).Decode(&gameArchive); err != nil {
+		return fmt.Errorf("[handlers.importArchive] failed to decode archive: %w", werrors.ErrBadRequest)
+	}
+	game, err := a.controller.Import(ctx, &gameArchive)
+	if err != nil {
+		return fmt.Errorf("[handlers.importArchive]: %w", err)
+	}
+	web.Respond(ctx, w, game, http.StatusCreated)
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jacobtie/rating-party/server/internal/config"
 	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/controllers/rating"
 	"github.com/jacobtie/rating-party/server/internal/middleware"
 	"github.com/jacobtie/rating-party/server/internal/platform/db"
 	"github.com/jacobtie/rating-party/server/internal/platform/web"
@@ -27,11 +28,24 @@ func registerGameRoutes(service *web.Service, cfg *config.Config, db *db.DB) {
 	service.Handle(http.MethodGet, "/api/v1/games/:gameId", router.getSingleGame, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
 	service.Handle(http.MethodPost, "/api/v1/games", router.createGame, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
 	service.Handle(http.MethodPut, "/api/v1/games/:gameId", router.updateGame, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPut, "/api/v1/games/:gameId/ranking", router.updateGameRanking, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
 	service.Handle(http.MethodDelete, "/api/v1/games/:gameId", router.deleteGame, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPost, "/api/v1/games/:gameId/code", router.regenerateGameCode, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPut, "/api/v1/games/:gameId/code", router.updateJoinSettings, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPost, "/api/v1/games/:gameId/restore", router.restoreGame, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
 }
 
+// getAllGames lists the active games, or the soft deleted games that can still be restored when deleted=true
 func (g *gameRouter) getAllGames(w http.ResponseWriter, r *http.Request) error {
 	ctx := r.Context()
+	if r.URL.Query().Get("deleted") == "true" {
+		games, err := g.controller.GetAllDeleted(ctx)
+		if err != nil {
+			return fmt.Errorf("[handlers.getAllGames]: %w", err)
+		}
+		web.Respond(ctx, w, games, http.StatusOK)
+		return nil
+	}
 	games, err := g.controller.GetAll(ctx)
 	if err != nil {
 		return fmt.Errorf("[handlers.getAllGames]: %w", err)
@@ -115,6 +129,45 @@ func (g *gameRouter) updateGame(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+type updateGameRankingRequest struct {
+	RankingStrategy string   `json:"rankingStrategy"`
+	TieBreakers     []string `json:"tieBreakers"`
+	// MergeDuplicates is optional so clients that predate it keep the current setting
+	MergeDuplicates *bool `json:"mergeDuplicates"`
+}
+
+func (g *gameRouter) updateGameRanking(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.updateGameRanking] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.updateGameRanking] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.updateGameRanking] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	var req updateGameRankingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("[handlers.updateGameRanking] failed to decode request: %w", werrors.ErrBadRequest)
+	}
+	if !rating.IsValidStrategy(req.RankingStrategy) {
+		return fmt.Errorf("[handlers.updateGameRanking] unknown ranking strategy %q: %w", req.RankingStrategy, werrors.ErrBadRequest)
+	}
+	for _, tieBreaker := range req.TieBreakers {
+		if !rating.IsValidTieBreaker(tieBreaker) {
+			return fmt.Errorf("[handlers.updateGameRanking] unknown tie breaker %q: %w", tieBreaker, werrors.ErrBadRequest)
+		}
+	}
+	if err := g.controller.UpdateRanking(ctx, gameID, req.RankingStrategy, req.TieBreakers, req.MergeDuplicates); err != nil {
+		return fmt.Errorf("[handlers.updateGameRanking]: %w", err)
+	}
+	web.Respond(ctx, w, nil, http.StatusNoContent)
+	return nil
+}
+
 func (g *gameRouter) deleteGame(w http.ResponseWriter, r *http.Request) error {
 	ctx := r.Context()
 	params := httprouter.ParamsFromContext(ctx)
@@ -135,3 +188,70 @@ func (g *gameRouter) deleteGame(w http.ResponseWriter, r *http.Request) error {
 	web.Respond(ctx, w, game, http.StatusOK)
 	return nil
 }
+
+func (g *gameRouter) restoreGame(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.restoreGame] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.restoreGame] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.restoreGame] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	game, err := g.controller.Restore(ctx, gameID)
+	if err != nil {
+		return fmt.Errorf("[handlers.restoreGame]: %w", err)
+	}
+	web.Respond(ctx, w, game, http.StatusOK)
+	return nil
+}
+
+// regenerateGameCode replaces a leaked game code, participants who already joined are not affected
+func (g *gameRouter) regenerateGameCode(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.regenerateGameCode] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.regenerateGameCode] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.regenerateGameCode] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	game, err := g.controller.RegenerateCode(ctx, gameID)
+	if err != nil {
+		return fmt.Errorf("[handlers.regenerateGameCode]: %w", err)
+	}
+	web.Respond(ctx, w, game, http.StatusOK)
+	return nil
+}
+
+func (g *gameRouter) updateJoinSettings(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.updateJoinSettings] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.updateJoinSettings] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.updateJoinSettings] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	var req game.JoinSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("[handlers.updateJoinSettings] failed to decode request: %w", werrors.ErrBadRequest)
+	}
+	if err := g.controller.UpdateJoinSettings(ctx, gameID, req); err != nil {
+		return fmt.Errorf("[handlers.updateJoinSettings]: %w", err)
+	}
+	web.Respond(ctx, w, nil, http.StatusNoContent)
+	return nil
+}
@@ -10,13 +10,18 @@ import (
 	"github.com/jacobtie/rating-party/server/internal/platform/web"
 )
 
-func NewAPI(cfg *config.Config, db *db.DB, clientDir fs.FS) http.Handler {
+func NewAPI(cfg *config.Config, db *db.DB, clientDir fs.FS, build BuildInfo) http.Handler {
 	service := web.NewService(middleware.ErrorHandlerMW, middleware.RequestLoggerMW)
 	registerSessionRoutes(service, cfg, db)
 	registerGameRoutes(service, cfg, db)
 	registerWineRoutes(service, cfg, db)
 	registerParticipantRoutes(service, cfg, db)
 	registerRatingRoutes(service, cfg, db)
+	registerArchiveRoutes(service, cfg, db)
+	registerTemplateRoutes(service, cfg, db)
+	registerKeyRoutes(service)
+	registerStatsRoutes(service, cfg, db)
+	registerHealthRoutes(service, cfg, db, build)
 	// Serve SPA
 	service.ServeSPA(clientDir)
 	return service
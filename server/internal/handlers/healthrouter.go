@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/logger"
+	"github.com/jacobtie/rating-party/server/internal/platform/web"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// BuildInfo holds the BUILD_ variables from cmd/main.go
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	BuildDate string `json:"buildDate"`
+}
+
+type versionResponse struct {
+	BuildInfo
+	Instance string `json:"instance"`
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+type healthRouter struct {
+	cfg   *config.Config
+	db    *db.DB
+	build BuildInfo
+}
+
+// The probes skip authentication and request logging since orchestrators poll them constantly
+func registerHealthRoutes(service *web.Service, cfg *config.Config, db *db.DB, build BuildInfo) {
+	router := &healthRouter{cfg: cfg, db: db, build: build}
+	service.HandleQuiet(http.MethodGet, "/healthz", router.getHealth)
+	service.HandleQuiet(http.MethodGet, "/readyz", router.getReadiness)
+	service.HandleQuiet(http.MethodGet, "/version", router.getVersion)
+}
+
+// getHealth only shows the process is up and serving, restarting it will not fix the database
+func (h *healthRouter) getHealth(w http.ResponseWriter, r *http.Request) error {
+	web.Respond(r.Context(), w, &healthResponse{Status: "ok"}, http.StatusOK)
+	return nil
+}
+
+// getReadiness reports whether requests can be served, which needs the database up with the schema applied
+func (h *healthRouter) getReadiness(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	err := h.db.PingContext(ctx)
+	if err == nil {
+		err = h.db.CheckSchema(ctx)
+	}
+	if err != nil {
+		// The reason is only logged since the probe is public and errors can name database hosts and users
+		logger.Get().Warn().Err(err).Msg("readiness check failed")
+		web.Respond(r.Context(), w, &healthResponse{Status: "unavailable"}, http.StatusServiceUnavailable)
+		return nil
+	}
+	web.Respond(r.Context(), w, &healthResponse{Status: "ok"}, http.StatusOK)
+	return nil
+}
+
+func (h *healthRouter) getVersion(w http.ResponseWriter, r *http.Request) error {
+	web.Respond(r.Context(), w, &versionResponse{BuildInfo: h.build, Instance: h.cfg.Instance}, http.StatusOK)
+	return nil
+}
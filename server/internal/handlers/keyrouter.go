@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jacobtie/rating-party/server/internal/platform/jwtkeys"
+	"github.com/jacobtie/rating-party/server/internal/platform/web"
+)
+
+type keyRouter struct{}
+
+func registerKeyRoutes(service *web.Service) {
+	router := &keyRouter{}
+	service.Handle(http.MethodGet, "/.well-known/jwks.json", router.getJWKS)
+}
+
+// getJWKS publishes the public keys so other services can verify our tokens
+func (k *keyRouter) getJWKS(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	keys, err := jwtkeys.Get()
+	if err != nil {
+		return fmt.Errorf("[handlers.getJWKS]: %w", err)
+	}
+	// Verifiers may cache the keys briefly, a new key is published well before it signs anything
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	web.Respond(ctx, w, keys.JWKS(), http.StatusOK)
+	return nil
+}
@@ -3,21 +3,34 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
 	"github.com/jacobtie/rating-party/server/internal/controllers/participant"
 	"github.com/jacobtie/rating-party/server/internal/controllers/rating"
 	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
 	"github.com/jacobtie/rating-party/server/internal/middleware"
 	"github.com/jacobtie/rating-party/server/internal/platform/contextvalue"
 	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/pdfdoc"
+	"github.com/jacobtie/rating-party/server/internal/platform/spreadsheet"
 	"github.com/jacobtie/rating-party/server/internal/platform/web"
 	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
 	"github.com/julienschmidt/httprouter"
 )
 
+// maxImportSize caps the size of an uploaded ratings CSV
+const maxImportSize = 1 << 20
+
+// maxBlankScorecards caps how many unnamed scorecards can be printed at once
+const maxBlankScorecards = 100
+
 type ratingRouter struct {
 	controller *rating.Controller
 }
@@ -27,12 +40,22 @@ func registerRatingRoutes(service *web.Service, cfg *config.Config, db *db.DB) {
 		controller: rating.NewController(
 			cfg,
 			db,
+			game.NewController(cfg, db),
 			participant.NewController(cfg, db),
 			wine.NewController(cfg, db),
 		),
 	}
 	service.Handle(http.MethodGet, "/api/v1/games/:gameId/ratings", router.getRatings, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
 	service.Handle(http.MethodGet, "/api/v1/games/:gameId/ratings/results", router.getRatingsResult, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
+	service.Handle(http.MethodGet, "/api/v1/games/:gameId/ratings/export", router.exportRatings, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
+	service.Handle(http.MethodGet, "/api/v1/games/:gameId/ratings/results/export", router.exportRatingsResult, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
+	service.Handle(http.MethodGet, "/api/v1/games/:gameId/ratings/results/report", router.getResultsReport, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
+	service.Handle(http.MethodPut, "/api/v1/games/:gameId/ratings/proxy", router.putProxyRatings, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPost, "/api/v1/games/:gameId/ratings/import", router.importRatings, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodGet, "/api/v1/games/:gameId/scorecards", router.getScorecards, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodGet, "/api/v1/games/:gameId/ratings/agreement", router.getAgreementReport, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
+	service.Handle(http.MethodGet, "/api/v1/games/:gameId/ratings/duplicates", router.getDuplicateReport, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodGet, "/api/v1/games/:gameId/wines/:wineId/ratings", router.getWineDetail, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
 	service.Handle(http.MethodPut, "/api/v1/games/:gameId/wines/:wineId/ratings", router.putRating, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
 }
 
@@ -89,7 +112,15 @@ func (rr *ratingRouter) getRatingsResult(w http.ResponseWriter, r *http.Request)
 	if !ok {
 		return fmt.Errorf("[handlers.getRatingsResult] no values in context")
 	}
-	results, err := rr.controller.GetRatingsResult(ctx, gameID, v.IsAdmin)
+	query := r.URL.Query()
+	postHocMethod := query.Get("posthoc")
+	if postHocMethod != "" && !rating.IsValidPostHocMethod(postHocMethod) {
+		return fmt.Errorf("[handlers.getRatingsResult] unknown post-hoc method %q: %w", postHocMethod, werrors.ErrBadRequest)
+	}
+	results, err := rr.controller.GetRatingsResult(ctx, gameID, v.IsAdmin, rating.ResultOptions{
+		IncludeStatistics: query.Get("stats") == "true",
+		PostHocMethod:     rating.PostHocMethod(postHocMethod),
+	})
 	if err != nil {
 		return fmt.Errorf("[handlers.getRatingsResult]: could not get ratings result: %w", err)
 	}
@@ -97,6 +128,219 @@ func (rr *ratingRouter) getRatingsResult(w http.ResponseWriter, r *http.Request)
 	return nil
 }
 
+func (rr *ratingRouter) exportRatingsResult(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.exportRatingsResult] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.exportRatingsResult] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.exportRatingsResult] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
+	if !ok {
+		return fmt.Errorf("[handlers.exportRatingsResult] no values in context")
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = spreadsheet.FormatCSV
+	}
+	file, err := rr.controller.ExportResults(ctx, gameID, v.IsAdmin, format)
+	if err != nil {
+		return fmt.Errorf("[handlers.exportRatingsResult]: could not export ratings result: %w", err)
+	}
+	web.RespondFile(ctx, w, file.Data, file.ContentType, file.Filename, http.StatusOK)
+	return nil
+}
+
+func (rr *ratingRouter) getResultsReport(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.getResultsReport] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.getResultsReport] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.getResultsReport] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
+	if !ok {
+		return fmt.Errorf("[handlers.getResultsReport] no values in context")
+	}
+	data, filename, err := rr.controller.ResultsReport(ctx, gameID, v.IsAdmin)
+	if err != nil {
+		return fmt.Errorf("[handlers.getResultsReport]: could not get results report: %w", err)
+	}
+	web.RespondFile(ctx, w, data, pdfdoc.ContentType, filename, http.StatusOK)
+	return nil
+}
+
+func (rr *ratingRouter) getScorecards(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.getScorecards] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.getScorecards] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.getScorecards] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	blankCards := 0
+	if blank := r.URL.Query().Get("blank"); blank != "" {
+		parsed, err := strconv.Atoi(blank)
+		if err != nil || parsed < 0 || parsed > maxBlankScorecards {
+			return fmt.Errorf("[handlers.getScorecards] blank must be a number between 0 and %d: %w", maxBlankScorecards, werrors.ErrBadRequest)
+		}
+		blankCards = parsed
+	}
+	data, filename, err := rr.controller.Scorecards(ctx, gameID, blankCards)
+	if err != nil {
+		return fmt.Errorf("[handlers.getScorecards]: could not get scorecards: %w", err)
+	}
+	web.RespondFile(ctx, w, data, pdfdoc.ContentType, filename, http.StatusOK)
+	return nil
+}
+
+func (rr *ratingRouter) exportRatings(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.exportRatings] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.exportRatings] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.exportRatings] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
+	if !ok {
+		return fmt.Errorf("[handlers.exportRatings] no values in context")
+	}
+	participantID := ""
+	if !v.IsAdmin {
+		if v.UserID == "" {
+			return fmt.Errorf("[handlers.exportRatings] user ID was not found")
+		}
+		participantID = v.UserID
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = spreadsheet.FormatCSV
+	}
+	file, err := rr.controller.ExportRatings(ctx, gameID, participantID, format)
+	if err != nil {
+		return fmt.Errorf("[handlers.exportRatings]: could not export ratings: %w", err)
+	}
+	web.RespondFile(ctx, w, file.Data, file.ContentType, file.Filename, http.StatusOK)
+	return nil
+}
+
+func (rr *ratingRouter) getAgreementReport(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.getAgreementReport] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.getAgreementReport] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.getAgreementReport] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
+	if !ok {
+		return fmt.Errorf("[handlers.getAgreementReport] no values in context")
+	}
+	participantID := ""
+	if !v.IsAdmin {
+		if v.UserID == "" {
+			return fmt.Errorf("[handlers.getAgreementReport] user ID was not found")
+		}
+		participantID = v.UserID
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" {
+		file, err := rr.controller.ExportAgreementReport(ctx, gameID, participantID, format)
+		if err != nil {
+			return fmt.Errorf("[handlers.getAgreementReport]: could not export agreement report: %w", err)
+		}
+		web.RespondFile(ctx, w, file.Data, file.ContentType, file.Filename, http.StatusOK)
+		return nil
+	}
+	report, err := rr.controller.GetAgreementReport(ctx, gameID, participantID)
+	if err != nil {
+		return fmt.Errorf("[handlers.getAgreementReport]: could not get agreement report: %w", err)
+	}
+	web.Respond(ctx, w, report, http.StatusOK)
+	return nil
+}
+
+func (rr *ratingRouter) getDuplicateReport(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.getDuplicateReport] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.getDuplicateReport] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.getDuplicateReport] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	report, err := rr.controller.GetDuplicateReport(ctx, gameID)
+	if err != nil {
+		return fmt.Errorf("[handlers.getDuplicateReport]: could not get duplicate report: %w", err)
+	}
+	web.Respond(ctx, w, report, http.StatusOK)
+	return nil
+}
+
+func (rr *ratingRouter) getWineDetail(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.getWineDetail] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.getWineDetail] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.getWineDetail] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	wineID := params.ByName("wineId")
+	if wineID == "" {
+		return fmt.Errorf("[handlers.getWineDetail] wine ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(wineID); err != nil {
+		return fmt.Errorf("[handlers.getWineDetail] wine ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
+	if !ok {
+		return fmt.Errorf("[handlers.getWineDetail] no values in context")
+	}
+	detail, err := rr.controller.GetWineDetail(ctx, gameID, wineID, v.IsAdmin)
+	if err != nil {
+		return fmt.Errorf("[handlers.getWineDetail]: could not get wine detail: %w", err)
+	}
+	web.Respond(ctx, w, detail, http.StatusOK)
+	return nil
+}
+
 type putRatingRequest struct {
 	SightRating   float64 `json:"sightRating"`
 	AromaRating   float64 `json:"aromaRating"`
@@ -152,3 +396,79 @@ func (rr *ratingRouter) putRating(w http.ResponseWriter, r *http.Request) error
 	web.Respond(ctx, w, rating, http.StatusOK)
 	return nil
 }
+
+type putProxyRatingsRequest struct {
+	Username string                `json:"username"`
+	Ratings  []*rating.ProxyRating `json:"ratings"`
+}
+
+func (rr *ratingRouter) putProxyRatings(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.putProxyRatings] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.putProxyRatings] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.putProxyRatings] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	var req putProxyRatingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("[handlers.putProxyRatings] failed to decode request body: %w", werrors.ErrBadRequest)
+	}
+	if strings.TrimSpace(req.Username) == "" {
+		return fmt.Errorf("[handlers.putProxyRatings] username was not found: %w", werrors.ErrBadRequest)
+	}
+	for _, entry := range req.Ratings {
+		if entry == nil {
+			return fmt.Errorf("[handlers.putProxyRatings] rating was empty: %w", werrors.ErrBadRequest)
+		}
+		entry.Username = req.Username
+	}
+	result, err := rr.controller.ImportRatings(ctx, gameID, req.Ratings)
+	if err != nil {
+		return fmt.Errorf("[handlers.putProxyRatings]: %w", err)
+	}
+	web.Respond(ctx, w, result, http.StatusOK)
+	return nil
+}
+
+// importRatings accepts a CSV file either as the raw request body or as the "file" field of a multipart form.
+// The username query parameter is used for rows without a username.
+func (rr *ratingRouter) importRatings(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.importRatings] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.importRatings] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.importRatings] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return fmt.Errorf("[handlers.importRatings] no file in form: %w", werrors.ErrBadRequest)
+		}
+		defer file.Close()
+		body = file
+	}
+	entries, err := rating.ParseRatingsCSV(body, strings.TrimSpace(r.URL.Query().Get("username")))
+	if err != nil {
+		return fmt.Errorf("[handlers.importRatings]: %w", err)
+	}
+	result, err := rr.controller.ImportRatings(ctx, gameID, entries)
+	if err != nil {
+		return fmt.Errorf("[handlers.importRatings]: %w", err)
+	}
+	web.Respond(ctx, w, result, http.StatusOK)
+	return nil
+}
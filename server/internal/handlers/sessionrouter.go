@@ -1,15 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
 	"github.com/jacobtie/rating-party/server/internal/controllers/session"
+	"github.com/jacobtie/rating-party/server/internal/middleware"
+	"github.com/jacobtie/rating-party/server/internal/platform/contextvalue"
 	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/qrimage"
 	"github.com/jacobtie/rating-party/server/internal/platform/web"
 	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultQRCodeSize = 512
+	maxQRCodeSize     = 2048
 )
 
 type sessionRouter struct {
@@ -18,9 +32,18 @@ type sessionRouter struct {
 
 func registerSessionRoutes(server *web.Service, cfg *config.Config, db *db.DB) {
 	router := &sessionRouter{
-		controller: session.NewController(cfg, db),
+		controller: session.NewController(cfg, db, game.NewController(cfg, db)),
 	}
 	server.Handle(http.MethodPost, "/api/v1/signin", router.signIn)
+	server.Handle(http.MethodPost, "/api/v1/signin/invite", router.signInWithInvite)
+	server.Handle(http.MethodPost, "/api/v1/games/:gameId/invites", router.createInvite, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	server.Handle(http.MethodGet, "/api/v1/games/:gameId/invites/qr", router.getInviteQRCode, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	server.Handle(http.MethodPost, "/api/v1/token/refresh", router.refreshToken)
+	server.Handle(http.MethodPost, "/api/v1/signout", router.signOut, middleware.AuthenticateMW)
+	server.Handle(http.MethodGet, "/api/v1/sessions", router.getActiveSessions, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	server.Handle(http.MethodDelete, "/api/v1/sessions/:sessionId", router.revokeSession, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	server.Handle(http.MethodGet, "/api/v1/signin/lockouts", router.getLockouts, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	server.Handle(http.MethodDelete, "/api/v1/signin/lockouts/:kind/:value", router.clearLockout, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
 }
 
 type signInRequest struct {
@@ -37,10 +60,225 @@ func (s *sessionRouter) signIn(w http.ResponseWriter, r *http.Request) error {
 	if request.Username == "" {
 		return fmt.Errorf("[handlers.signIn] username is required: %w", werrors.ErrBadRequest)
 	}
-	res, err := s.controller.SignIn(ctx, request.Username, request.Passcode)
+	res, err := s.controller.SignIn(ctx, request.Username, request.Passcode, sessionClient(r))
 	if err != nil {
 		return fmt.Errorf("[handlers.signIn] failed to sign in: %w", err)
 	}
 	web.Respond(r.Context(), w, res, http.StatusOK)
 	return nil
 }
+
+func (s *sessionRouter) getLockouts(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	web.Respond(ctx, w, s.controller.GetLockouts(), http.StatusOK)
+	return nil
+}
+
+func (s *sessionRouter) clearLockout(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.clearLockout] no params in context: %w", werrors.ErrBadRequest)
+	}
+	kind := params.ByName("kind")
+	value := params.ByName("value")
+	if kind == "" || value == "" {
+		return fmt.Errorf("[handlers.clearLockout] lockout was not found: %w", werrors.ErrBadRequest)
+	}
+	if err := s.controller.ClearLockout(kind, value); err != nil {
+		return fmt.Errorf("[handlers.clearLockout]: %w", err)
+	}
+	web.Respond(ctx, w, nil, http.StatusNoContent)
+	return nil
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (s *sessionRouter) refreshToken(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	var request refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return fmt.Errorf("[handlers.refreshToken] failed to decode body with error: %v: %w", err, werrors.ErrBadRequest)
+	}
+	if request.RefreshToken == "" {
+		return fmt.Errorf("[handlers.refreshToken] refresh token is required: %w", werrors.ErrBadRequest)
+	}
+	res, err := s.controller.Refresh(ctx, request.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("[handlers.refreshToken] failed to refresh token: %w", err)
+	}
+	web.Respond(ctx, w, res, http.StatusOK)
+	return nil
+}
+
+func sessionClient(r *http.Request) session.Client {
+	return session.Client{
+		IP:        web.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// signOut revokes the caller's own token so it cannot be used again
+func (s *sessionRouter) signOut(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
+	if !ok {
+		return fmt.Errorf("[handlers.signOut] failed to cast context values")
+	}
+	if err := s.controller.SignOut(ctx, v.TokenID); err != nil {
+		return fmt.Errorf("[handlers.signOut]: %w", err)
+	}
+	web.Respond(ctx, w, nil, http.StatusNoContent)
+	return nil
+}
+
+// getActiveSessions lists the sessions that have not expired or been revoked, optionally only those
+// for one game (gameId) or only the admin's own sessions (admin=true)
+func (s *sessionRouter) getActiveSessions(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	query := r.URL.Query()
+	filter := session.SessionFilter{
+		GameID:    query.Get("gameId"),
+		AdminOnly: query.Get("admin") == "true",
+	}
+	if filter.GameID != "" {
+		if _, err := uuid.Parse(filter.GameID); err != nil {
+			return fmt.Errorf("[handlers.getActiveSessions] game ID was not a UUID: %w", werrors.ErrBadRequest)
+		}
+	}
+	sessions, err := s.controller.GetActiveSessions(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("[handlers.getActiveSessions]: %w", err)
+	}
+	web.Respond(ctx, w, sessions, http.StatusOK)
+	return nil
+}
+
+func (s *sessionRouter) revokeSession(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.revokeSession] no params in context: %w", werrors.ErrBadRequest)
+	}
+	sessionID := params.ByName("sessionId")
+	if sessionID == "" {
+		return fmt.Errorf("[handlers.revokeSession] session ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return fmt.Errorf("[handlers.revokeSession] session ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	if err := s.controller.RevokeSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("[handlers.revokeSession]: %w", err)
+	}
+	web.Respond(ctx, w, nil, http.StatusNoContent)
+	return nil
+}
+
+type signInWithInviteRequest struct {
+	Invite   string `json:"invite"`
+	Username string `json:"username"`
+}
+
+func (s *sessionRouter) signInWithInvite(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	var request signInWithInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return fmt.Errorf("[handlers.signInWithInvite] failed to decode body with error: %v: %w", err, werrors.ErrBadRequest)
+	}
+	if request.Invite == "" {
+		return fmt.Errorf("[handlers.signInWithInvite] invite is required: %w", werrors.ErrBadRequest)
+	}
+	res, err := s.controller.SignInWithInvite(ctx, request.Invite, request.Username, sessionClient(r))
+	if err != nil {
+		return fmt.Errorf("[handlers.signInWithInvite] failed to sign in: %w", err)
+	}
+	web.Respond(ctx, w, res, http.StatusOK)
+	return nil
+}
+
+type createInviteRequest struct {
+	Username string `json:"username"`
+	// ValidForHours defaults to the configured invite lifetime when zero
+	ValidForHours int `json:"validForHours"`
+}
+
+func (s *sessionRouter) createInvite(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	gameID, err := inviteGameID(ctx)
+	if err != nil {
+		return fmt.Errorf("[handlers.createInvite]: %w", err)
+	}
+	var request createInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return fmt.Errorf("[handlers.createInvite] failed to decode body with error: %v: %w", err, werrors.ErrBadRequest)
+	}
+	if request.ValidForHours < 0 {
+		return fmt.Errorf("[handlers.createInvite] valid for hours cannot be negative: %w", werrors.ErrBadRequest)
+	}
+	invite, err := s.controller.CreateInvite(ctx, gameID, request.Username, time.Duration(request.ValidForHours)*time.Hour)
+	if err != nil {
+		return fmt.Errorf("[handlers.createInvite]: %w", err)
+	}
+	web.Respond(ctx, w, invite, http.StatusCreated)
+	return nil
+}
+
+// getInviteQRCode mints a fresh invite and returns a QR code image of its link, ready to print on a table card
+func (s *sessionRouter) getInviteQRCode(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	gameID, err := inviteGameID(ctx)
+	if err != nil {
+		return fmt.Errorf("[handlers.getInviteQRCode]: %w", err)
+	}
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = qrimage.FormatPNG
+	}
+	if !qrimage.IsValidFormat(format) {
+		return fmt.Errorf("[handlers.getInviteQRCode] unknown format %q: %w", format, werrors.ErrBadRequest)
+	}
+	size := defaultQRCodeSize
+	if rawSize := query.Get("size"); rawSize != "" {
+		parsed, err := strconv.Atoi(rawSize)
+		if err != nil || parsed < 64 || parsed > maxQRCodeSize {
+			return fmt.Errorf("[handlers.getInviteQRCode] size must be a number between 64 and %d: %w", maxQRCodeSize, werrors.ErrBadRequest)
+		}
+		size = parsed
+	}
+	var validFor time.Duration
+	if hours := query.Get("validForHours"); hours != "" {
+		parsed, err := strconv.Atoi(hours)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("[handlers.getInviteQRCode] valid for hours must be a positive number: %w", werrors.ErrBadRequest)
+		}
+		validFor = time.Duration(parsed) * time.Hour
+	}
+	invite, err := s.controller.CreateInvite(ctx, gameID, query.Get("username"), validFor)
+	if err != nil {
+		return fmt.Errorf("[handlers.getInviteQRCode]: %w", err)
+	}
+	data, err := qrimage.Encode(invite.URL, format, size)
+	if err != nil {
+		return fmt.Errorf("[handlers.getInviteQRCode]: %w", err)
+	}
+	web.RespondFile(ctx, w, data, qrimage.ContentType(format), "invite."+format, http.StatusOK)
+	return nil
+}
+
+func inviteGameID(ctx context.Context) (string, error) {
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return "", fmt.Errorf("[handlers.inviteGameID] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return "", fmt.Errorf("[handlers.inviteGameID] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return "", fmt.Errorf("[handlers.inviteGameID] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	return gameID, nil
+}
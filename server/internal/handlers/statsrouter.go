@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/middleware"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/metrics"
+	"github.com/jacobtie/rating-party/server/internal/platform/web"
+)
+
+type statsRouter struct {
+	db *db.DB
+}
+
+func registerStatsRoutes(service *web.Service, cfg *config.Config, db *db.DB) {
+	router := &statsRouter{db: db}
+	service.Handle(http.MethodGet, "/api/v1/stats/db", router.getDBStats, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	// Metrics normally get their own listener, the API only serves them when that is turned off
+	if cfg.Web.MetricsHost == "" {
+		service.Handle(http.MethodGet, "/metrics", router.getMetrics, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	}
+}
+
+// getDBStats shows how busy the connection pool is, a growing wait count means the pool is too small
+func (s *statsRouter) getDBStats(w http.ResponseWriter, r *http.Request) error {
+	web.Respond(r.Context(), w, s.db.PoolStats(), http.StatusOK)
+	return nil
+}
+
+func (s *statsRouter) getMetrics(w http.ResponseWriter, r *http.Request) error {
+	metrics.Handler().ServeHTTP(w, r)
+	return nil
+}
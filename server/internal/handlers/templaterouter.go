@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/controllers/template"
+	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
+	"github.com/jacobtie/rating-party/server/internal/middleware"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/web"
+	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
+	"github.com/julienschmidt/httprouter"
+)
+
+type templateRouter struct {
+	controller *template.Controller
+}
+
+func registerTemplateRoutes(service *web.Service, cfg *config.Config, db *db.DB) {
+	router := &templateRouter{
+		controller: template.NewController(cfg, db, game.NewController(cfg, db), wine.NewController(cfg, db)),
+	}
+	service.Handle(http.MethodPost, "/api/v1/games/:gameId/clone", router.cloneGame, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPost, "/api/v1/games/:gameId/template", router.saveTemplate, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodGet, "/api/v1/templates", router.getAllTemplates, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPost, "/api/v1/templates/:templateId/games", router.createGameFromTemplate, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodDelete, "/api/v1/templates/:templateId", router.deleteTemplate, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+}
+
+type cloneGameRequest struct {
+	GameName string `json:"gameName"`
+	template.CopyOptions
+}
+
+func (t *templateRouter) cloneGame(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.cloneGame] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.cloneGame] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.cloneGame] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	var req cloneGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("[handlers.cloneGame] failed to decode request: %w", werrors.ErrBadRequest)
+	}
+	game, err := t.controller.CloneGame(ctx, gameID, req.GameName, req.CopyOptions)
+	if err != nil {
+		return fmt.Errorf("[handlers.cloneGame]: %w", err)
+	}
+	web.Respond(ctx, w, game, http.StatusCreated)
+	return nil
+}
+
+type saveTemplateRequest struct {
+	TemplateName string `json:"templateName"`
+	template.CopyOptions
+}
+
+func (t *templateRouter) saveTemplate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.saveTemplate] no params in context: %w", werrors.ErrBadRequest)
+	}
+	gameID := params.ByName("gameId")
+	if gameID == "" {
+		return fmt.Errorf("[handlers.saveTemplate] game ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[handlers.saveTemplate] game ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	var req saveTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("[handlers.saveTemplate] failed to decode request: %w", werrors.ErrBadRequest)
+	}
+	saved, err := t.controller.SaveTemplate(ctx, gameID, req.TemplateName, req.CopyOptions)
+	if err != nil {
+		return fmt.Errorf("[handlers.saveTemplate]: %w", err)
+	}
+	web.Respond(ctx, w, saved, http.StatusCreated)
+	return nil
+}
+
+func (t *templateRouter) getAllTemplates(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	templates, err := t.controller.GetAllTemplates(ctx)
+	if err != nil {
+		return fmt.Errorf("[handlers.getAllTemplates]: %w", err)
+	}
+	web.Respond(ctx, w, templates, http.StatusOK)
+	return nil
+}
+
+type createGameFromTemplateRequest struct {
+	GameName string `json:"gameName"`
+}
+
+func (t *templateRouter) createGameFromTemplate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.createGameFromTemplate] no params in context: %w", werrors.ErrBadRequest)
+	}
+	templateID := params.ByName("templateId")
+	if templateID == "" {
+		return fmt.Errorf("[handlers.createGameFromTemplate] template ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(templateID); err != nil {
+		return fmt.Errorf("[handlers.createGameFromTemplate] template ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	var req createGameFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("[handlers.createGameFromTemplate] failed to decode request: %w", werrors.ErrBadRequest)
+	}
+	game, err := t.controller.CreateGameFromTemplate(ctx, templateID, req.GameName)
+	if err != nil {
+		return fmt.Errorf("[handlers.createGameFromTemplate]: %w", err)
+	}
+	web.Respond(ctx, w, game, http.StatusCreated)
+	return nil
+}
+
+func (t *templateRouter) deleteTemplate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	if params == nil {
+		return fmt.Errorf("[handlers.deleteTemplate] no params in context: %w", werrors.ErrBadRequest)
+	}
+	templateID := params.ByName("templateId")
+	if templateID == "" {
+		return fmt.Errorf("[handlers.deleteTemplate] template ID was not found: %w", werrors.ErrBadRequest)
+	}
+	if _, err := uuid.Parse(templateID); err != nil {
+		return fmt.Errorf("[handlers.deleteTemplate] template ID was not a UUID: %w", werrors.ErrBadRequest)
+	}
+	deleted, err := t.controller.DeleteTemplate(ctx, templateID)
+	if err != nil {
+		return fmt.Errorf("[handlers.deleteTemplate]: %w", err)
+	}
+	web.Respond(ctx, w, deleted, http.StatusOK)
+	return nil
+}
@@ -28,7 +28,9 @@ func registerWineRoutes(service *web.Service, cfg *config.Config, db *db.DB) {
 	service.Handle(http.MethodGet, "/api/v1/games/:gameId/wines/:wineId", router.getSingleWine, middleware.MakeAuthorizationMW(false), middleware.AuthenticateMW)
 	service.Handle(http.MethodPost, "/api/v1/games/:gameId/wines", router.createWine, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
 	service.Handle(http.MethodPut, "/api/v1/games/:gameId/wines/:wineId", router.updateWine, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPut, "/api/v1/games/:gameId/wines/:wineId/duplicate", router.setWineDuplicate, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
 	service.Handle(http.MethodDelete, "/api/v1/games/:gameId/wines/:wineId", router.deleteWine, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
+	service.Handle(http.MethodPost, "/api/v1/games/:gameId/wines/:wineId/restore", router.restoreWine, middleware.MakeAuthorizationMW(true), middleware.AuthenticateMW)
 }
 
 func (wr *wineRouter) getAllWines(w http.ResponseWriter, r *http.Request) error {
@@ -45,6 +47,18 @@ func (wr *wineRouter) getAllWines(w http.ResponseWriter, r *http.Request) error
 	if !ok {
 		return fmt.Errorf("[wineRouter.getAllWines] failed to get context values")
 	}
+	// The admin can list the deleted wines that can still be restored with deleted=true
+	if r.URL.Query().Get("deleted") == "true" {
+		if !v.IsAdmin {
+			return fmt.Errorf("[wineRouter.getAllWines] only the admin can list deleted wines: %w", werrors.ErrForbidden)
+		}
+		wines, err := wr.controller.GetAllDeletedWines(ctx, gameID)
+		if err != nil {
+			return fmt.Errorf("[wineRouter.getAllWines] failed to get deleted wines: %w", err)
+		}
+		web.Respond(ctx, w, wines, http.StatusOK)
+		return nil
+	}
 	wines, err := wr.controller.GetAllWines(ctx, gameID)
 	if err != nil {
 		return fmt.Errorf("[wineRouter.getAllWines] failed to get all wines: %w", err)
@@ -53,6 +67,7 @@ func (wr *wineRouter) getAllWines(w http.ResponseWriter, r *http.Request) error
 		for i := range wines {
 			wines[i].WineName = ""
 			wines[i].WineYear = 0
+			wines[i].DuplicateOfWineID = ""
 		}
 	}
 	web.Respond(ctx, w, wines, http.StatusOK)
@@ -63,10 +78,17 @@ func (wr *wineRouter) getSingleWine(w http.ResponseWriter, r *http.Request) erro
 	ctx := r.Context()
 	params := httprouter.ParamsFromContext(ctx)
 	wineID := params.ByName("wineId")
+	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
+	if !ok {
+		return fmt.Errorf("[wineRouter.getSingleWine] failed to get context values")
+	}
 	wine, err := wr.controller.GetSingleWine(ctx, wineID)
 	if err != nil {
 		return fmt.Errorf("[wineRouter.getSingleWine] failed to get single wine: %w", err)
 	}
+	if !v.IsAdmin {
+		wine.DuplicateOfWineID = ""
+	}
 	web.Respond(ctx, w, wine, http.StatusOK)
 	return nil
 }
@@ -126,6 +148,31 @@ func (wr *wineRouter) updateWine(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+type setWineDuplicateRequest struct {
+	DuplicateOfWineID string `json:"duplicateOfWineId"`
+}
+
+func (wr *wineRouter) setWineDuplicate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	gameID := params.ByName("gameId")
+	wineID := params.ByName("wineId")
+	var req setWineDuplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("[wineRouter.setWineDuplicate] failed to decode request body: %w", werrors.ErrBadRequest)
+	}
+	if req.DuplicateOfWineID != "" {
+		if _, err := uuid.Parse(req.DuplicateOfWineID); err != nil {
+			return fmt.Errorf("[wineRouter.setWineDuplicate] invalid duplicate of wine id: %w", werrors.ErrBadRequest)
+		}
+	}
+	if err := wr.controller.SetDuplicateOf(ctx, gameID, wineID, req.DuplicateOfWineID); err != nil {
+		return fmt.Errorf("[wineRouter.setWineDuplicate] failed to set duplicate: %w", err)
+	}
+	web.Respond(ctx, w, nil, http.StatusNoContent)
+	return nil
+}
+
 func (wr *wineRouter) deleteWine(w http.ResponseWriter, r *http.Request) error {
 	ctx := r.Context()
 	params := httprouter.ParamsFromContext(ctx)
@@ -137,3 +184,22 @@ func (wr *wineRouter) deleteWine(w http.ResponseWriter, r *http.Request) error {
 	web.Respond(ctx, w, wine, http.StatusOK)
 	return nil
 }
+
+func (wr *wineRouter) restoreWine(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	gameID := params.ByName("gameId")
+	if _, err := uuid.Parse(gameID); err != nil {
+		return fmt.Errorf("[wineRouter.restoreWine] invalid game id: %w", werrors.ErrBadRequest)
+	}
+	wineID := params.ByName("wineId")
+	if _, err := uuid.Parse(wineID); err != nil {
+		return fmt.Errorf("[wineRouter.restoreWine] invalid wine id: %w", werrors.ErrBadRequest)
+	}
+	wine, err := wr.controller.RestoreWine(ctx, gameID, wineID)
+	if err != nil {
+		return fmt.Errorf("[wineRouter.restoreWine] failed to restore wine: %w", err)
+	}
+	web.Respond(ctx, w, wine, http.StatusOK)
+	return nil
+}
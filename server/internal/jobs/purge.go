@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/controllers/wine"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/logger"
+)
+
+// RunPurge permanently removes soft deleted games and wines once their retention window has passed.
+// It checks straight away and then every purge interval until the context is cancelled.
+func RunPurge(ctx context.Context, cfg *config.Config, db *db.DB) {
+	gameController := game.NewController(cfg, db)
+	wineController := wine.NewController(cfg, db)
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		deletedBefore := time.Now().UTC().Add(-cfg.GameRetention)
+		purged, err := gameController.PurgeDeleted(ctx, deletedBefore)
+		if err != nil {
+			logger.Get().Err(err).Int("purged", purged).Msg("failed to purge deleted games")
+		} else if purged > 0 {
+			logger.Get().Info().Int("purged", purged).Msg("purged deleted games")
+		}
+		purged, err = wineController.PurgeDeleted(ctx, deletedBefore)
+		if err != nil {
+			logger.Get().Err(err).Msg("failed to purge deleted wines")
+		} else if purged > 0 {
+			logger.Get().Info().Int("purged", purged).Msg("purged deleted wines")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacobtie/rating-party/server/internal/config"
+	"github.com/jacobtie/rating-party/server/internal/controllers/game"
+	"github.com/jacobtie/rating-party/server/internal/controllers/session"
+	"github.com/jacobtie/rating-party/server/internal/platform/db"
+	"github.com/jacobtie/rating-party/server/internal/platform/logger"
+)
+
+// RunRevocationSync keeps the in memory list of revoked sessions in step with the database.
+// It syncs straight away and then every sync interval until the context is cancelled.
+func RunRevocationSync(ctx context.Context, cfg *config.Config, db *db.DB) {
+	controller := session.NewController(cfg, db, game.NewController(cfg, db))
+	ticker := time.NewTicker(cfg.RevocationSyncInterval)
+	defer ticker.Stop()
+	for {
+		if err := controller.SyncRevocations(ctx); err != nil {
+			logger.Get().Err(err).Msg("failed to sync revoked sessions")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
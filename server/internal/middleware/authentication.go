@@ -7,36 +7,47 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
-	"github.com/jacobtie/rating-party/server/internal/config"
 	"github.com/jacobtie/rating-party/server/internal/platform/contextvalue"
+	"github.com/jacobtie/rating-party/server/internal/platform/jwtkeys"
+	"github.com/jacobtie/rating-party/server/internal/platform/revocation"
 	"github.com/jacobtie/rating-party/server/internal/platform/web"
 	"github.com/jacobtie/rating-party/server/internal/platform/werrors"
 )
 
+const accessTokenAudience = "rating-party"
+
 func AuthenticateMW(next web.Handler) web.Handler {
 	return func(w http.ResponseWriter, r *http.Request) error {
-		cfg, err := config.Get()
-		if err != nil {
-			return fmt.Errorf("[middleware.AuthenticateMW] failed to get config: %w", err)
-		}
 		v, ok := r.Context().Value(contextvalue.KeyValues).(*contextvalue.Values)
 		if !ok {
 			return fmt.Errorf("[middleware.AuthenticateMW] failed to cast context values")
 		}
 		authHeader := r.Header.Get("Authorization")
-		parsedToken, err := parseToken(cfg, authHeader)
+		keys, err := jwtkeys.Get()
 		if err != nil {
+			return fmt.Errorf("[middleware.AuthenticateMW] failed to get signing keys: %w", err)
+		}
+		parsedToken, err := parseToken(keys, authHeader)
+		if err != nil {
+			return err
+		}
+		if err := validateAudience(parsedToken); err != nil {
 			return err
 		}
 		if err := validateExpiration(parsedToken); err != nil {
 			return err
 		}
+		tokenID, err := validateTokenID(parsedToken)
+		if err != nil {
+			return err
+		}
 		v.JWT = parsedToken
+		v.TokenID = tokenID
 		return next(w, r)
 	}
 }
 
-func parseToken(cfg *config.Config, authHeader string) (*jwt.Token, error) {
+func parseToken(keys *jwtkeys.Keyring, authHeader string) (*jwt.Token, error) {
 	if authHeader == "" {
 		return nil, fmt.Errorf("[middleware.AuthenticateMW] no auth header: %w", werrors.ErrUnauthorized)
 	}
@@ -45,18 +56,26 @@ func parseToken(cfg *config.Config, authHeader string) (*jwt.Token, error) {
 		return nil, fmt.Errorf("[middleware.AuthenticateMW] malformed auth header: %w", werrors.ErrUnauthorized)
 	}
 	token := authHeaderSegments[1]
-	parsedToken, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("invalid signing method from JWT")
-		}
-		return []byte(cfg.AdminJWTSecret), nil
-	})
+	parsedToken, err := jwt.Parse(token, keys.Keyfunc)
 	if err != nil {
 		return nil, fmt.Errorf("[middleware.AuthenticateMW] failed to parse token: %w", err)
 	}
 	return parsedToken, nil
 }
 
+// validateAudience only accepts access tokens. Invites are signed with the same keys, so without this
+// an invite would pass as a token for its game.
+func validateAudience(parsedToken *jwt.Token) error {
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return fmt.Errorf("[middleware.AuthenticateMW] failed to cast jwt claims: %w", werrors.ErrUnauthorized)
+	}
+	if !claims.VerifyAudience(accessTokenAudience, true) {
+		return fmt.Errorf("[middleware.AuthenticateMW] token is not an access token: %w", werrors.ErrUnauthorized)
+	}
+	return nil
+}
+
 func validateExpiration(parsedToken *jwt.Token) error {
 	claims, ok := parsedToken.Claims.(jwt.MapClaims)
 	if !ok {
@@ -75,3 +94,20 @@ func validateExpiration(parsedToken *jwt.Token) error {
 	}
 	return nil
 }
+
+// validateTokenID rejects tokens whose session has been revoked. Tokens without a jti were issued before
+// sessions were tracked and cannot be revoked, so they must sign in again.
+func validateTokenID(parsedToken *jwt.Token) (string, error) {
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", fmt.Errorf("[middleware.AuthenticateMW] failed to cast jwt claims: %w", werrors.ErrUnauthorized)
+	}
+	tokenID, ok := claims["jti"].(string)
+	if !ok || tokenID == "" {
+		return "", fmt.Errorf("[middleware.AuthenticateMW] could not find jti on jwt: %w", werrors.ErrUnauthorized)
+	}
+	if revocation.IsRevoked(tokenID) {
+		return "", fmt.Errorf("[middleware.AuthenticateMW] session has been revoked: %w", werrors.ErrUnauthorized)
+	}
+	return tokenID, nil
+}
@@ -5,12 +5,14 @@ import (
 
 	"github.com/jacobtie/rating-party/server/internal/platform/logger"
 	"github.com/jacobtie/rating-party/server/internal/platform/web"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func ErrorHandlerMW(before web.Handler) web.Handler {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		if err := before(w, r); err != nil {
 			logger.GetCtx(r.Context()).Err(err).Msg("error handler middleware")
+			trace.SpanFromContext(r.Context()).RecordError(err)
 			web.HandleError(r.Context(), w, err)
 		}
 		return nil
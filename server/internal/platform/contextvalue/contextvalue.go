@@ -12,6 +12,7 @@ const KeyValues ctxKey = 1
 
 type Values struct {
 	JWT          *jwt.Token
+	TokenID      string
 	UserID       string
 	IsAdmin      bool
 	RequestID    string
@@ -4,31 +4,122 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jacobtie/rating-party/server/internal/config"
 	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
 )
 
 type DB struct {
 	*sqlx.DB
 }
 
-func New(un, pw, host, dbName string) (*DB, error) {
-	dsn := fmt.Sprintf("postgresql://%s:%s@%s/%s", un, pw, host, dbName)
-	postgres, err := sqlx.Open("pgx", dsn)
+const (
+	pingTimeout       = 3 * time.Second
+	firstConnectRetry = 500 * time.Millisecond
+	maxConnectRetry   = 10 * time.Second
+)
+
+// New opens the connection pool and waits for the database to answer, retrying with backoff until the
+// connect timeout so the server can start alongside a database that is still starting up
+func New(ctx context.Context, cfg *config.Config) (*DB, error) {
+	dsn, err := DSN(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("[db.New] could not open connection to DB: %w", err)
+		return nil, fmt.Errorf("[db.New] failed to build connection string: %w", err)
 	}
-	// Test the connection
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	if err := postgres.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("[db.New] could not complete initial DB ping: %w", err)
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("[db.New] could not parse connection string: %w", err)
+	}
+	connConfig.Tracer = &queryTracer{}
+	postgres := sqlx.NewDb(stdlib.OpenDB(*connConfig), "pgx")
+	postgres.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	postgres.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	postgres.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+	postgres.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime)
+	deadline := time.Now().Add(cfg.DB.ConnectTimeout)
+	wait := firstConnectRetry
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx, postgres)
+		if err == nil {
+			break
+		}
+		if time.Now().Add(wait).After(deadline) {
+			postgres.Close()
+			return nil, fmt.Errorf("[db.New] could not reach DB after %d attempts: %w", attempt, err)
+		}
+		log.Warn().Err(err).Int("attempt", attempt).Str("retryIn", wait.String()).Msg("database is not reachable yet")
+		select {
+		case <-ctx.Done():
+			postgres.Close()
+			return nil, fmt.Errorf("[db.New] stopped waiting for DB: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+		wait *= 2
+		if wait > maxConnectRetry {
+			wait = maxConnectRetry
+		}
 	}
 	return &DB{postgres}, nil
 }
 
+func ping(ctx context.Context, postgres *sqlx.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return postgres.PingContext(ctx)
+}
+
+// DSN builds the connection URL, escaping the credentials so passwords may contain any character
+func DSN(cfg *config.Config) (string, error) {
+	query, err := url.ParseQuery(cfg.DB.Options)
+	if err != nil {
+		return "", fmt.Errorf("[db.DSN] failed to parse DB options: %w", err)
+	}
+	if cfg.DB.SSLMode != "" {
+		query.Set("sslmode", cfg.DB.SSLMode)
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.DB.DBUser, cfg.DB.DBPass),
+		Host:     cfg.DB.DBURI,
+		Path:     "/" + cfg.DB.DBName,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String(), nil
+}
+
+// PoolStats is a snapshot of the connection pool for monitoring. Waits and closed connections count up from startup.
+type PoolStats struct {
+	MaxOpenConnections int   `json:"maxOpenConnections"`
+	OpenConnections    int   `json:"openConnections"`
+	InUse              int   `json:"inUse"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"waitCount"`
+	WaitDurationMs     int64 `json:"waitDurationMs"`
+	MaxIdleClosed      int64 `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64 `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64 `json:"maxLifetimeClosed"`
+}
+
+func (db *DB) PoolStats() *PoolStats {
+	stats := db.Stats()
+	return &PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
 func (db *DB) WithTransaction(ctx context.Context, fn func(*sqlx.Tx) error) error {
 	tx, err := db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
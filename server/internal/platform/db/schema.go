@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expectedSchema is every table and column in schema.sql, keep it in step when the schema changes
+var expectedSchema = map[string][]string{
+	"game":               {"game_id", "game_name", "game_code", "is_running", "are_results_shared", "ranking_strategy", "tie_breakers", "merge_duplicates", "code_disabled", "disable_code_when_stopped", "code_expires_at", "max_joins", "join_count", "code_version", "created_at", "updated_at", "deleted_at"},
+	"participant":        {"participant_id", "game_id", "username", "created_at", "updated_at", "deleted_at"},
+	"wine":               {"wine_id", "wine_name", "wine_code", "wine_year", "game_id", "duplicate_of", "created_at", "updated_at", "deleted_at"},
+	"rating":             {"rating_id", "game_id", "participant_id", "wine_id", "sight_rating", "aroma_rating", "taste_rating", "overall_rating", "comments", "host_entered", "created_at", "updated_at", "deleted_at"},
+	"user_session":       {"session_id", "participant_id", "game_id", "is_admin", "ip_address", "user_agent", "created_at", "expires_at", "revoked_at"},
+	"refresh_token":      {"token_hash", "session_id", "created_at", "expires_at", "used_at"},
+	"game_template":      {"template_id", "template_name", "ranking_strategy", "tie_breakers", "merge_duplicates", "code_disabled", "disable_code_when_stopped", "max_joins", "created_at", "updated_at"},
+	"game_template_wine": {"template_wine_id", "template_id", "wine_name", "wine_code", "wine_year", "duplicate_of", "created_at", "updated_at"},
+}
+
+// CheckSchema makes sure every table and column the server queries exists, so a database that has not
+// had the latest schema changes applied is reported as not ready rather than failing requests
+func (db *DB) CheckSchema(ctx context.Context) error {
+	rows, err := db.QueryxContext(ctx, `
+		SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = current_schema();
+	`)
+	if err != nil {
+		return fmt.Errorf("[db.CheckSchema] failed to query columns: %w", err)
+	}
+	defer rows.Close()
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return fmt.Errorf("[db.CheckSchema] failed to scan row: %w", err)
+		}
+		existing[table+"."+column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("[db.CheckSchema] failed to read columns: %w", err)
+	}
+	missing := make([]string, 0)
+	for table, columns := range expectedSchema {
+		for _, column := range columns {
+			if !existing[table+"."+column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("[db.CheckSchema] schema.sql has not been fully applied, run it again to upgrade the database, missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jacobtie/rating-party/server/internal/platform/metrics"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type queryStartKey struct{}
+
+// queryTracer times every query run through the pool and traces it as a child of the span in the context.
+// The statement is recorded without its arguments, which can hold passcodes and token hashes.
+type queryTracer struct{}
+
+func (q *queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	op := operation(data.SQL)
+	ctx, span := tracing.Start(ctx, "db."+op, semconv.DBSystemPostgreSQL, semconv.DBOperation(op), semconv.DBStatement(strings.TrimSpace(data.SQL)))
+	return context.WithValue(ctx, queryStartKey{}, queryStart{at: time.Now(), operation: op, span: span})
+}
+
+func (q *queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	defer start.span.End()
+	outcome := "ok"
+	if data.Err != nil {
+		outcome = "error"
+		tracing.Fail(start.span, data.Err)
+	}
+	metrics.DBQueryDuration.WithLabelValues(start.operation, outcome).Observe(time.Since(start.at).Seconds())
+}
+
+type queryStart struct {
+	at        time.Time
+	operation string
+	span      trace.Span
+}
+
+// operation is the statement type, anything unusual is grouped together to keep the label small
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "OTHER"
+	}
+	switch keyword := strings.ToUpper(fields[0]); keyword {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return keyword
+	}
+	return "OTHER"
+}
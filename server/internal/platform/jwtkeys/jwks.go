@@ -0,0 +1,104 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmES256 = "ES256"
+)
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// JWKS publishes the public half of every asymmetric key. HMAC keys are secret and never published,
+// so tokens signed with them can only be verified by this server.
+func (k *Keyring) JWKS() *JWKS {
+	jwks := &JWKS{Keys: make([]*JWK, 0)}
+	for _, key := range k.Keys() {
+		switch public := key.verifyKey.(type) {
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, &JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: AlgorithmEdDSA,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		case *ecdsa.PublicKey:
+			// Coordinates are padded to the full curve size as the JWK spec requires
+			x := make([]byte, 32)
+			y := make([]byte, 32)
+			public.X.FillBytes(x)
+			public.Y.FillBytes(y)
+			jwks.Keys = append(jwks.Keys, &JWK{
+				KeyType:   "EC",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: AlgorithmES256,
+				Curve:     "P-256",
+				X:         base64.RawURLEncoding.EncodeToString(x),
+				Y:         base64.RawURLEncoding.EncodeToString(y),
+			})
+		}
+	}
+	return jwks
+}
+
+// Generate writes a new private key to the keys directory and returns its key ID, which is based on the date
+// so keys sort in the order they were created
+func Generate(dir, algorithm string) (string, error) {
+	var private interface{}
+	var err error
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return "", fmt.Errorf("[jwtkeys.Generate] unsupported algorithm %q, use %s or %s", algorithm, AlgorithmEdDSA, AlgorithmES256)
+	}
+	if err != nil {
+		return "", fmt.Errorf("[jwtkeys.Generate] failed to generate key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", fmt.Errorf("[jwtkeys.Generate] failed to encode key: %w", err)
+	}
+	keyID := fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102-150405"), strings.ToLower(algorithm))
+	path := filepath.Join(dir, keyID+privateKeySuffix)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("[jwtkeys.Generate] failed to create key file: %w", err)
+	}
+	defer file.Close()
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return "", fmt.Errorf("[jwtkeys.Generate] failed to write key file: %w", err)
+	}
+	return keyID, nil
+}
@@ -0,0 +1,245 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/jacobtie/rating-party/server/internal/config"
+)
+
+// DefaultKeyID names the HMAC key made from AdminJWTSecret. Tokens without a kid were signed before
+// key IDs existed and are verified with it. There is no default key when the secret is left empty,
+// which is only allowed when a key file is the signing key.
+const DefaultKeyID = "default"
+
+// Key files in the keys directory are named after their key ID:
+//
+//	<kid>.pem      private Ed25519 or P-256 key, used to sign when it is the signing key and to verify
+//	<kid>.pub.pem  public key only, for verifying tokens from a retired key whose private half is gone
+//	<kid>.secret   HMAC secret
+const (
+	privateKeySuffix = ".pem"
+	publicKeySuffix  = ".pub.pem"
+	secretSuffix     = ".secret"
+)
+
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// Keyring holds every key that tokens may be verified with, and the one key new tokens are signed with
+type Keyring struct {
+	keys    map[string]*Key
+	signing *Key
+}
+
+var (
+	mu      sync.Mutex
+	keyring *Keyring
+)
+
+// Get loads the keyring from the config the first time it is called
+func Get() (*Keyring, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if keyring == nil {
+		cfg, err := config.Get()
+		if err != nil {
+			return nil, fmt.Errorf("[jwtkeys.Get] failed to get config: %w", err)
+		}
+		loaded, err := Load(cfg)
+		if err != nil {
+			return nil, err
+		}
+		keyring = loaded
+	}
+	return keyring, nil
+}
+
+func Load(cfg *config.Config) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*Key)}
+	if cfg.AdminJWTSecret != "" {
+		k.add(hmacKey(DefaultKeyID, []byte(cfg.AdminJWTSecret)))
+	}
+	if cfg.JWT.KeysDir != "" {
+		if err := k.loadDir(cfg.JWT.KeysDir); err != nil {
+			return nil, fmt.Errorf("[jwtkeys.Load] failed to load keys: %w", err)
+		}
+	}
+	signingKeyID := cfg.JWT.SigningKeyID
+	if signingKeyID == "" {
+		signingKeyID = DefaultKeyID
+	}
+	signing, ok := k.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("[jwtkeys.Load] signing key %q was not found", signingKeyID)
+	}
+	if signing.signKey == nil {
+		return nil, fmt.Errorf("[jwtkeys.Load] signing key %q only has a public key", signingKeyID)
+	}
+	k.signing = signing
+	return k, nil
+}
+
+func (k *Keyring) add(key *Key) {
+	k.keys[key.ID] = key
+}
+
+func (k *Keyring) loadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		var key *Key
+		switch {
+		case strings.HasSuffix(name, publicKeySuffix):
+			key, err = parsePublicKey(strings.TrimSuffix(name, publicKeySuffix), data)
+		case strings.HasSuffix(name, privateKeySuffix):
+			key, err = parsePrivateKey(strings.TrimSuffix(name, privateKeySuffix), data)
+		case strings.HasSuffix(name, secretSuffix):
+			secret := strings.TrimSpace(string(data))
+			if len(secret) < 32 {
+				err = fmt.Errorf("secret must be at least 32 characters")
+			}
+			key = hmacKey(strings.TrimSuffix(name, secretSuffix), []byte(secret))
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("key file %s: %w", name, err)
+		}
+		// A private key already covers verification, so a matching public key file is redundant
+		if existing, ok := k.keys[key.ID]; ok && existing.signKey != nil && key.signKey == nil {
+			continue
+		}
+		k.add(key)
+	}
+	return nil
+}
+
+func hmacKey(id string, secret []byte) *Key {
+	return &Key{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+func parsePrivateKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	var private interface{}
+	var err error
+	if block.Type == "EC PRIVATE KEY" {
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	} else {
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch private := private.(type) {
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, signKey: private, verifyKey: private.Public()}, nil
+	case *ecdsa.PrivateKey:
+		if private.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("only P-256 EC keys are supported")
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodES256, signKey: private, verifyKey: &private.PublicKey}, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", private)
+}
+
+func parsePublicKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch public := public.(type) {
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: public}, nil
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("only P-256 EC keys are supported")
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodES256, verifyKey: public}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", public)
+}
+
+// Sign signs the claims with the signing key and names it in the kid header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	token.Header["kid"] = k.signing.ID
+	signed, err := token.SignedString(k.signing.signKey)
+	if err != nil {
+		return "", fmt.Errorf("[jwtkeys.Sign] failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// Keyfunc finds the key named by the token's kid for jwt.Parse. The token must use the key's own
+// algorithm, so a public key can never be used as an HMAC secret.
+func (k *Keyring) Keyfunc(t *jwt.Token) (interface{}, error) {
+	keyID := DefaultKeyID
+	if kid, ok := t.Header["kid"]; ok {
+		kidString, ok := kid.(string)
+		if !ok {
+			return nil, fmt.Errorf("kid header was not a string")
+		}
+		keyID = kidString
+	}
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", keyID, t.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// Keys lists the key IDs sorted by name, marking the signing key
+func (k *Keyring) Keys() []*Key {
+	keys := make([]*Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func (k *Keyring) SigningKeyID() string {
+	return k.signing.ID
+}
@@ -7,14 +7,19 @@ import (
 	"github.com/jacobtie/rating-party/server/internal/platform/contextvalue"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var globalLogger *zerolog.Logger
 
-func InitLogger(environment config.ENV, instance, version, revision, buildDate string) {
-	log.Level(zerolog.InfoLevel)
+// InitLogger logs at the level given, or when it is empty at debug locally and info in production
+func InitLogger(environment config.ENV, level, instance, version, revision, buildDate string) {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	if environment == config.ENV_LOCAL {
-		log.Level(zerolog.DebugLevel)
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+	if parsed, err := zerolog.ParseLevel(level); err == nil && level != "" {
+		zerolog.SetGlobalLevel(parsed)
 	}
 	l := log.With().
 		Str("instance", instance).
@@ -29,12 +34,20 @@ func Get() *zerolog.Logger {
 	return globalLogger
 }
 
+// GetCtx adds the request details to the logger, and the trace and span IDs so logs can be matched to traces
 func GetCtx(ctx context.Context) *zerolog.Logger {
+	logContext := globalLogger.With()
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		logContext = logContext.
+			Str("traceId", spanContext.TraceID().String()).
+			Str("spanId", spanContext.SpanID().String())
+	}
 	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
 	if !ok {
-		return Get()
+		l := logContext.Logger()
+		return &l
 	}
-	l := globalLogger.With().
+	l := logContext.
 		Str("requestId", v.RequestID).
 		Str("requestStart", v.RequestStart.String()).
 		Str("method", v.Method).
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace       = "ratingparty"
+	activityTimeout = 5 * time.Second
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests handled, by route pattern and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Time taken by database queries, by statement type and whether they failed.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "outcome"})
+)
+
+// Handler serves every registered metric in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest records a handled request. The route is the pattern the handler was registered with
+// rather than the path, so game and wine IDs do not create a series each.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	HTTPRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	HTTPRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// RegisterDB publishes the connection pool statistics
+func RegisterDB(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Activity is what is going on at the parties right now
+type Activity struct {
+	RunningGames       int
+	ActiveParticipants int
+	// RatingsByGame counts the ratings in each running game by game ID
+	RatingsByGame map[string]int
+}
+
+type ActivityFunc func(ctx context.Context) (*Activity, error)
+
+// RegisterActivity publishes the activity gauges, which are read from the database on each scrape
+func RegisterActivity(fn ActivityFunc) error {
+	return prometheus.Register(&activityCollector{fn: fn})
+}
+
+var (
+	runningGamesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "games", "running"),
+		"Games that are running and accepting ratings.",
+		nil, nil,
+	)
+	activeParticipantsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "participants", "active"),
+		"Participants with a session that has not expired or been revoked.",
+		nil, nil,
+	)
+	ratingsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "ratings", "submitted"),
+		"Ratings submitted in each running game.",
+		[]string{"game_id"}, nil,
+	)
+)
+
+type activityCollector struct {
+	fn ActivityFunc
+}
+
+func (a *activityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- runningGamesDesc
+	ch <- activeParticipantsDesc
+	ch <- ratingsDesc
+}
+
+func (a *activityCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), activityTimeout)
+	defer cancel()
+	activity, err := a.fn(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(runningGamesDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(runningGamesDesc, prometheus.GaugeValue, float64(activity.RunningGames))
+	ch <- prometheus.MustNewConstMetric(activeParticipantsDesc, prometheus.GaugeValue, float64(activity.ActiveParticipants))
+	for gameID, ratings := range activity.RatingsByGame {
+		ch <- prometheus.MustNewConstMetric(ratingsDesc, prometheus.GaugeValue, float64(ratings), gameID)
+	}
+}
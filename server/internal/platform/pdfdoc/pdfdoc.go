@@ -0,0 +1,214 @@
+package pdfdoc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+const ContentType = "application/pdf"
+
+const (
+	margin     = 15.0
+	lineHeight = 6.0
+	fontFamily = "Helvetica"
+)
+
+// Document wraps an A4 fpdf document with the headings, paragraphs and tables used by our reports
+type Document struct {
+	pdf *fpdf.Fpdf
+	tr  func(string) string
+}
+
+func New(title string) *Document {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Rating Party", true)
+	pdf.AliasNbPages("")
+	d := &Document{
+		pdf: pdf,
+		// The core fonts only support cp1252, so text is translated from UTF-8
+		tr: pdf.UnicodeTranslatorFromDescriptor(""),
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin + 5)
+		pdf.SetFont(fontFamily, "I", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, 5, d.tr(fmt.Sprintf("%s - page %d of {nb}", title, pdf.PageNo())), "", 0, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+	return d
+}
+
+// PDF exposes the underlying fpdf document for custom drawing
+func (d *Document) PDF() *fpdf.Fpdf {
+	return d.pdf
+}
+
+// Tr translates UTF-8 text into the encoding used by the document fonts
+func (d *Document) Tr(text string) string {
+	return d.tr(text)
+}
+
+// SetFont switches to the document font in the given style, "" for regular, "B" for bold or "I" for italic
+func (d *Document) SetFont(style string, size float64) {
+	d.pdf.SetFont(fontFamily, style, size)
+}
+
+// ContentWidth returns the width of the page between the margins
+func (d *Document) ContentWidth() float64 {
+	width, _ := d.pdf.GetPageSize()
+	return width - 2*margin
+}
+
+// AddPage starts a new page
+func (d *Document) AddPage() {
+	d.pdf.AddPage()
+}
+
+func (d *Document) Title(text string) {
+	d.pdf.SetFont(fontFamily, "B", 18)
+	d.pdf.MultiCell(0, 9, d.tr(text), "", "L", false)
+	d.pdf.Ln(2)
+}
+
+func (d *Document) Subtitle(text string) {
+	d.pdf.SetFont(fontFamily, "", 10)
+	d.pdf.SetTextColor(96, 96, 96)
+	d.pdf.MultiCell(0, 5, d.tr(text), "", "L", false)
+	d.pdf.SetTextColor(0, 0, 0)
+	d.pdf.Ln(4)
+}
+
+func (d *Document) Heading(text string) {
+	d.ensureSpace(3 * lineHeight)
+	d.pdf.Ln(2)
+	d.pdf.SetFont(fontFamily, "B", 13)
+	d.pdf.CellFormat(0, 8, d.tr(text), "B", 1, "L", false, 0, "")
+	d.pdf.Ln(2)
+}
+
+func (d *Document) Paragraph(text string) {
+	d.pdf.SetFont(fontFamily, "", 10)
+	d.pdf.MultiCell(0, 5, d.tr(text), "", "L", false)
+	d.pdf.Ln(1)
+}
+
+// KeyValues writes a two column list of labels and values
+func (d *Document) KeyValues(pairs [][2]string) {
+	labelWidth := d.ContentWidth() * 0.4
+	for _, pair := range pairs {
+		d.ensureSpace(lineHeight)
+		d.pdf.SetFont(fontFamily, "B", 10)
+		d.pdf.CellFormat(labelWidth, lineHeight, d.tr(pair[0]), "", 0, "L", false, 0, "")
+		d.pdf.SetFont(fontFamily, "", 10)
+		d.pdf.MultiCell(0, lineHeight, d.tr(pair[1]), "", "L", false)
+	}
+	d.pdf.Ln(2)
+}
+
+// Table writes a table with a bold header that is repeated on every page the table spans.
+// Columns share the content width equally when widths is nil, and text too long for a cell is truncated.
+func (d *Document) Table(header []string, widths []float64, rows [][]string) {
+	d.table(header, widths, rows, lineHeight)
+}
+
+// FormTable writes a table like Table with taller rows, leaving room to fill in the blank cells by hand
+func (d *Document) FormTable(header []string, widths []float64, rows [][]string, rowHeight float64) {
+	d.table(header, widths, rows, rowHeight)
+}
+
+func (d *Document) table(header []string, widths []float64, rows [][]string, rowHeight float64) {
+	if widths == nil {
+		widths = make([]float64, len(header))
+		for i := range widths {
+			widths[i] = d.ContentWidth() / float64(len(header))
+		}
+	}
+	writeHeader := func() {
+		d.pdf.SetFont(fontFamily, "B", 9)
+		d.pdf.SetFillColor(220, 220, 220)
+		for i, text := range header {
+			d.pdf.CellFormat(widths[i], lineHeight+1, d.fit(text, widths[i]), "1", 0, "L", true, 0, "")
+		}
+		d.pdf.Ln(-1)
+	}
+	d.ensureSpace(lineHeight + 2*rowHeight)
+	writeHeader()
+	d.pdf.SetFillColor(245, 245, 245)
+	for r, row := range rows {
+		if d.remaining() < rowHeight {
+			d.pdf.AddPage()
+			writeHeader()
+			d.pdf.SetFillColor(245, 245, 245)
+		}
+		d.pdf.SetFont(fontFamily, "", 9)
+		for i, text := range row {
+			d.pdf.CellFormat(widths[i], rowHeight, d.fit(text, widths[i]), "1", 0, "L", r%2 == 1, 0, "")
+		}
+		d.pdf.Ln(-1)
+	}
+	d.pdf.Ln(3)
+}
+
+// QRCode draws a QR code encoding content as a square of the given size with its top left corner at x, y
+func (d *Document) QRCode(content string, x, y, size float64) error {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("[pdfdoc.QRCode] failed to encode qr code: %w", err)
+	}
+	// The bitmap includes the quiet zone around the code
+	bitmap := code.Bitmap()
+	module := size / float64(len(bitmap))
+	d.pdf.SetFillColor(0, 0, 0)
+	for row, modules := range bitmap {
+		for col, dark := range modules {
+			if dark {
+				d.pdf.Rect(x+float64(col)*module, y+float64(row)*module, module, module, "F")
+			}
+		}
+	}
+	d.pdf.SetFillColor(255, 255, 255)
+	return nil
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("[pdfdoc.Bytes] failed to render pdf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fit translates the text and truncates it with an ellipsis so it fits in a cell of the given width
+func (d *Document) fit(text string, width float64) string {
+	translated := d.tr(text)
+	available := width - 2*d.pdf.GetCellMargin()
+	if d.pdf.GetStringWidth(translated) <= available {
+		return translated
+	}
+	ellipsis := "..."
+	for len(translated) > 0 && d.pdf.GetStringWidth(translated+ellipsis) > available {
+		translated = translated[:len(translated)-1]
+	}
+	return strings.TrimSpace(translated) + ellipsis
+}
+
+func (d *Document) remaining() float64 {
+	_, height := d.pdf.GetPageSize()
+	return height - margin - d.pdf.GetY()
+}
+
+// ensureSpace starts a new page when less than the given height is left on the current one
+func (d *Document) ensureSpace(height float64) {
+	if d.remaining() < height {
+		d.pdf.AddPage()
+	}
+}
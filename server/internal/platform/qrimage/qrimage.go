@@ -0,0 +1,72 @@
+package qrimage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	ContentTypePNG = "image/png"
+	ContentTypeSVG = "image/svg+xml"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+func IsValidFormat(format string) bool {
+	return format == FormatPNG || format == FormatSVG
+}
+
+func ContentType(format string) string {
+	if format == FormatSVG {
+		return ContentTypeSVG
+	}
+	return ContentTypePNG
+}
+
+// Encode renders a QR code for content in the format. Size is the width in pixels for PNG images,
+// SVG images scale to whatever size they are printed at.
+func Encode(content, format string, size int) ([]byte, error) {
+	switch format {
+	case FormatPNG:
+		data, err := qrcode.Encode(content, qrcode.Medium, size)
+		if err != nil {
+			return nil, fmt.Errorf("[qrimage.Encode] failed to encode png: %w", err)
+		}
+		return data, nil
+	case FormatSVG:
+		data, err := svg(content)
+		if err != nil {
+			return nil, fmt.Errorf("[qrimage.Encode] failed to encode svg: %w", err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("[qrimage.Encode] unknown format %q", format)
+}
+
+// svg draws one path with a unit square per dark module, including the quiet zone
+func svg(content string) ([]byte, error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	bitmap := code.Bitmap()
+	var path strings.Builder
+	for row, modules := range bitmap {
+		for col, dark := range modules {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", col, row)
+			}
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, len(bitmap), len(bitmap))
+	b.WriteString(`<rect width="100%" height="100%" fill="#fff"/>`)
+	fmt.Fprintf(&b, `<path fill="#000" d="%s"/>`, path.String())
+	b.WriteString("</svg>\n")
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,47 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+)
+
+// The list of revoked token IDs is kept in memory so authenticating a request never touches the database.
+// Only tokens that have not expired yet need to be remembered, which keeps the list small.
+var (
+	mu      sync.RWMutex
+	revoked = make(map[string]time.Time)
+)
+
+// Revoke rejects the token ID until the token would have expired anyway
+func Revoke(tokenID string, expiresAt time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	revoked[tokenID] = expiresAt
+}
+
+func IsRevoked(tokenID string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := revoked[tokenID]
+	return ok
+}
+
+// Replace swaps in the full list of revoked token IDs, dropping any that have expired
+func Replace(tokens map[string]time.Time) {
+	now := time.Now()
+	fresh := make(map[string]time.Time, len(tokens))
+	for tokenID, expiresAt := range tokens {
+		if expiresAt.After(now) {
+			fresh[tokenID] = expiresAt
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// Keep revocations made since the caller read the database
+	for tokenID, expiresAt := range revoked {
+		if _, ok := fresh[tokenID]; !ok && expiresAt.After(now) {
+			fresh[tokenID] = expiresAt
+		}
+	}
+	revoked = fresh
+}
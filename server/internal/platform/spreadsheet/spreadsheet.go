@@ -0,0 +1,130 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentTypeCSV  = "text/csv; charset=utf-8"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Sheet is a single table of data with a header row
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]any
+}
+
+func IsValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatXLSX
+}
+
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return ContentTypeXLSX
+	}
+	return ContentTypeCSV
+}
+
+// Write renders the sheets in the given format. CSV only holds a single table, so only the first sheet is written.
+func Write(format string, sheets ...*Sheet) ([]byte, error) {
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("[spreadsheet.Write] no sheets to write")
+	}
+	switch format {
+	case FormatCSV:
+		return WriteCSV(sheets[0])
+	case FormatXLSX:
+		return WriteXLSX(sheets...)
+	}
+	return nil, fmt.Errorf("[spreadsheet.Write] unknown format %q", format)
+}
+
+func WriteCSV(sheet *Sheet) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(sheet.Header); err != nil {
+		return nil, fmt.Errorf("[spreadsheet.WriteCSV] failed to write header: %w", err)
+	}
+	for _, row := range sheet.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatValue(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, fmt.Errorf("[spreadsheet.WriteCSV] failed to write row: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("[spreadsheet.WriteCSV] failed to flush: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Filename builds a download filename from a free text name such as a game name
+func Filename(name, suffix, format string) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		slug = "game"
+	}
+	return fmt.Sprintf("%s-%s.%s", slug, suffix, format)
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return neutralizeFormula(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// neutralizeFormula stops free text such as comments and usernames being run as a formula when the sheet is
+// opened, by starting text that a spreadsheet would read as a formula with a quote
+func neutralizeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// File is a rendered spreadsheet ready to be downloaded
+type File struct {
+	Data        []byte
+	ContentType string
+	Filename    string
+}
+
+// NewFile renders the sheets in the given format with a filename built from name and suffix
+func NewFile(format, name, suffix string, sheets ...*Sheet) (*File, error) {
+	data, err := Write(format, sheets...)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Data:        data,
+		ContentType: ContentType(format),
+		Filename:    Filename(name, suffix, format),
+	}, nil
+}
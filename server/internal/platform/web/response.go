@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/jacobtie/rating-party/server/internal/config"
 	"github.com/jacobtie/rating-party/server/internal/platform/contextvalue"
@@ -33,6 +36,15 @@ func HandleError(ctx context.Context, w http.ResponseWriter, err error) {
 		respondError(ctx, w, err, http.StatusForbidden)
 		return
 	}
+	if errors.Is(err, werrors.ErrTooManyRequests) {
+		var retryErr *werrors.RetryAfterError
+		if errors.As(err, &retryErr) {
+			// Round up so clients never retry a moment too early
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+		}
+		respondError(ctx, w, err, http.StatusTooManyRequests)
+		return
+	}
 	respondError(ctx, w, err, http.StatusInternalServerError)
 }
 
@@ -73,3 +85,16 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, code
 	w.WriteHeader(code)
 	w.Write(jsonData)
 }
+
+// RespondFile writes data as a downloadable file attachment
+func RespondFile(ctx context.Context, w http.ResponseWriter, data []byte, contentType, filename string, code int) {
+	v, ok := ctx.Value(contextvalue.KeyValues).(*contextvalue.Values)
+	if ok {
+		v.StatusCode = code
+	}
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(code)
+	w.Write(data)
+}
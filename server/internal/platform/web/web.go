@@ -3,12 +3,18 @@ package web
 import (
 	"context"
 	"io/fs"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jacobtie/rating-party/server/internal/config"
 	"github.com/jacobtie/rating-party/server/internal/platform/contextvalue"
+	"github.com/jacobtie/rating-party/server/internal/platform/metrics"
+	"github.com/jacobtie/rating-party/server/internal/platform/tracing"
 	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Service struct {
@@ -44,21 +50,69 @@ func (s *Service) Handle(verb, path string, handler Handler, mw ...Middleware) {
 		header.Set("Access-Control-Allow-Origin", "*")
 		header.Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 		header.Set("Access-Control-Allow-Headers", "*")
-		ctx := r.Context()
 		requestID := uuid.New().String()
+		ctx, span := tracing.StartRequest(r, path, attribute.String("request.id", requestID))
 		v := &contextvalue.Values{
 			RequestID:    requestID,
 			RequestStart: time.Now(),
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			IP:           ClientIP(r),
+			Host:         r.Host,
+			Referrer:     r.Referer(),
 		}
 		ctx = context.WithValue(ctx, contextvalue.KeyValues, v)
 		if err := wrappedHandler(w, r.WithContext(ctx)); err != nil {
-			HandleError(r.Context(), w, err)
-			return
+			HandleError(ctx, w, err)
+		}
+		status := v.StatusCode
+		if status == 0 {
+			status = http.StatusOK
 		}
+		metrics.ObserveRequest(verb, path, status, time.Since(v.RequestStart))
+		tracing.EndRequest(span, status)
 	}
 	s.router.HandlerFunc(verb, path, h)
 }
 
+// HandleQuiet registers a handler without the global middleware, for probes that are polled every few
+// seconds and would drown out real requests in the logs
+func (s *Service) HandleQuiet(verb, path string, handler Handler) {
+	s.router.HandlerFunc(verb, path, func(w http.ResponseWriter, r *http.Request) {
+		if err := handler(w, r); err != nil {
+			HandleError(r.Context(), w, err)
+		}
+	})
+}
+
+// ClientIP returns the address of the client making the request. The X-Forwarded-For header is only
+// trusted when the server is configured to run behind a proxy, otherwise clients could pick their own address.
+// Each proxy appends the address it received the request from, so the client address is the entry added by
+// the outermost trusted proxy, counting from the right. Entries further left were sent by the client.
+func ClientIP(r *http.Request) string {
+	cfg := config.MustGet()
+	if cfg.Web.TrustProxy {
+		forwarded := make([]string, 0)
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, address := range strings.Split(header, ",") {
+				forwarded = append(forwarded, strings.TrimSpace(address))
+			}
+		}
+		if len(forwarded) > 0 {
+			i := len(forwarded) - cfg.Web.TrustedProxyHops
+			if i < 0 {
+				i = 0
+			}
+			return forwarded[i]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
@@ -1,10 +1,27 @@
 package werrors
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrNotFound     = errors.New("not found")
-	ErrBadRequest   = errors.New("bad request")
-	ErrUnauthorized = errors.New("unauthorized")
-	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrTooManyRequests = errors.New("too many requests")
 )
+
+// RetryAfterError is a too many requests error that tells the client how long to wait before trying again
+type RetryAfterError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return "too many requests, retry after " + e.RetryAfter.String()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return ErrTooManyRequests
+}
@@ -1,41 +1,59 @@
--- Not using full migrations yet
+-- Not using full migrations yet. The whole file can be run again to upgrade an existing database, every
+-- statement is skipped when it has already been applied.
 
 USE ratingparty;
 
-CREATE TABLE game (
+CREATE TABLE IF NOT EXISTS game (
     game_id UUID,
     game_name VARCHAR(255) NOT NULL,
     game_code VARCHAR(255) NOT NULL,
     is_running BOOLEAN NOT NULL DEFAULT FALSE,
     are_results_shared BOOLEAN NOT NULL DEFAULT FALSE,
+    ranking_strategy VARCHAR(255) NOT NULL DEFAULT 'mean',
+    tie_breakers VARCHAR(255) NOT NULL DEFAULT '',
+    merge_duplicates BOOLEAN NOT NULL DEFAULT FALSE,
+    code_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    disable_code_when_stopped BOOLEAN NOT NULL DEFAULT FALSE,
+    code_expires_at TIMESTAMP,
+    max_joins INT NOT NULL DEFAULT 0,
+    join_count INT NOT NULL DEFAULT 0,
+    code_version INT NOT NULL DEFAULT 0,
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW(),
+    deleted_at TIMESTAMP,
     PRIMARY KEY (game_id)
 );
 
-CREATE TABLE participant (
+-- Game codes only need to be unique among games that have not been deleted
+CREATE UNIQUE INDEX IF NOT EXISTS game_code_active ON game (game_code) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS participant (
     participant_id UUID,
     game_id UUID NOT NULL,
     username VARCHAR(255) NOT NULL,
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW(),
+    deleted_at TIMESTAMP,
     PRIMARY KEY (participant_id),
-    FOREIGN KEY (game_id) REFERENCES game(game_id)
+    FOREIGN KEY (game_id) REFERENCES game(game_id) ON DELETE CASCADE
 );
 
-CREATE TABLE wine (
+CREATE TABLE IF NOT EXISTS wine (
     wine_id UUID,
     wine_name VARCHAR(255) NOT NULL,
     wine_code VARCHAR(255) NOT NULL,
     wine_year INT NOT NULL,
     game_id UUID NOT NULL,
+    duplicate_of UUID,
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW(),
+    deleted_at TIMESTAMP,
     PRIMARY KEY (wine_id),
-    FOREIGN KEY (game_id) REFERENCES game(game_id)
+    FOREIGN KEY (game_id) REFERENCES game(game_id) ON DELETE CASCADE,
+    FOREIGN KEY (duplicate_of) REFERENCES wine(wine_id) ON DELETE SET NULL
 );
 
-CREATE TABLE rating (
+CREATE TABLE IF NOT EXISTS rating (
     rating_id UUID,
     game_id UUID NOT NULL,
     participant_id UUID NOT NULL,
@@ -45,11 +63,111 @@ CREATE TABLE rating (
     taste_rating FLOAT NOT NULL,
     overall_rating FLOAT NOT NULL,
     comments TEXT NOT NULL,
+    host_entered BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW(),
+    deleted_at TIMESTAMP,
     PRIMARY KEY (rating_id),
-    FOREIGN KEY (game_id) REFERENCES game(game_id),
-    FOREIGN KEY (participant_id) REFERENCES participant(participant_id),
-    FOREIGN KEY (wine_id) REFERENCES wine(wine_id),
+    FOREIGN KEY (game_id) REFERENCES game(game_id) ON DELETE CASCADE,
+    FOREIGN KEY (participant_id) REFERENCES participant(participant_id) ON DELETE CASCADE,
+    FOREIGN KEY (wine_id) REFERENCES wine(wine_id) ON DELETE CASCADE,
     UNIQUE (participant_id, wine_id)
 );
+
+-- Every sign in starts a session, the session ID is the jti of every access token issued for it
+CREATE TABLE IF NOT EXISTS user_session (
+    session_id UUID,
+    participant_id UUID,
+    game_id UUID,
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    ip_address VARCHAR(255) NOT NULL DEFAULT '',
+    user_agent VARCHAR(1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    PRIMARY KEY (session_id),
+    FOREIGN KEY (game_id) REFERENCES game(game_id) ON DELETE CASCADE,
+    FOREIGN KEY (participant_id) REFERENCES participant(participant_id) ON DELETE CASCADE
+);
+
+-- Refresh tokens are stored hashed and can each be used once
+CREATE TABLE IF NOT EXISTS refresh_token (
+    token_hash VARCHAR(64),
+    session_id UUID NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    PRIMARY KEY (token_hash),
+    FOREIGN KEY (session_id) REFERENCES user_session(session_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS game_template (
+    template_id UUID,
+    template_name VARCHAR(255) NOT NULL,
+    ranking_strategy VARCHAR(255) NOT NULL DEFAULT 'mean',
+    tie_breakers VARCHAR(255) NOT NULL DEFAULT '',
+    merge_duplicates BOOLEAN NOT NULL DEFAULT FALSE,
+    code_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    disable_code_when_stopped BOOLEAN NOT NULL DEFAULT FALSE,
+    max_joins INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (template_id)
+);
+
+CREATE TABLE IF NOT EXISTS game_template_wine (
+    template_wine_id UUID,
+    template_id UUID NOT NULL,
+    wine_name VARCHAR(255) NOT NULL,
+    wine_code VARCHAR(255) NOT NULL,
+    wine_year INT NOT NULL,
+    duplicate_of UUID,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (template_wine_id),
+    FOREIGN KEY (template_id) REFERENCES game_template(template_id) ON DELETE CASCADE,
+    FOREIGN KEY (duplicate_of) REFERENCES game_template_wine(template_wine_id) ON DELETE SET NULL
+);
+
+-- Upgrades for databases created before the columns above were added. Tables created by the statements
+-- above already have every column, so these only change older databases.
+
+ALTER TABLE game ADD COLUMN IF NOT EXISTS ranking_strategy VARCHAR(255) NOT NULL DEFAULT 'mean';
+ALTER TABLE game ADD COLUMN IF NOT EXISTS tie_breakers VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE game ADD COLUMN IF NOT EXISTS merge_duplicates BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE game ADD COLUMN IF NOT EXISTS code_disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE game ADD COLUMN IF NOT EXISTS disable_code_when_stopped BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE game ADD COLUMN IF NOT EXISTS code_expires_at TIMESTAMP;
+ALTER TABLE game ADD COLUMN IF NOT EXISTS max_joins INT NOT NULL DEFAULT 0;
+ALTER TABLE game ADD COLUMN IF NOT EXISTS join_count INT NOT NULL DEFAULT 0;
+ALTER TABLE game ADD COLUMN IF NOT EXISTS code_version INT NOT NULL DEFAULT 0;
+ALTER TABLE game ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE participant ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE wine ADD COLUMN IF NOT EXISTS duplicate_of UUID;
+ALTER TABLE wine ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE rating ADD COLUMN IF NOT EXISTS host_entered BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE rating ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE game_template ADD COLUMN IF NOT EXISTS code_disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE game_template ADD COLUMN IF NOT EXISTS disable_code_when_stopped BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE game_template ADD COLUMN IF NOT EXISTS max_joins INT NOT NULL DEFAULT 0;
+
+-- Foreign keys are recreated so purging a deleted game removes everything that belongs to it. The names are
+-- the ones Postgres gave the unnamed keys in the first version of this file.
+BEGIN;
+ALTER TABLE participant DROP CONSTRAINT IF EXISTS participant_game_id_fkey;
+ALTER TABLE participant ADD CONSTRAINT participant_game_id_fkey FOREIGN KEY (game_id) REFERENCES game(game_id) ON DELETE CASCADE;
+ALTER TABLE wine DROP CONSTRAINT IF EXISTS wine_game_id_fkey;
+ALTER TABLE wine ADD CONSTRAINT wine_game_id_fkey FOREIGN KEY (game_id) REFERENCES game(game_id) ON DELETE CASCADE;
+ALTER TABLE wine DROP CONSTRAINT IF EXISTS wine_duplicate_of_fkey;
+ALTER TABLE wine ADD CONSTRAINT wine_duplicate_of_fkey FOREIGN KEY (duplicate_of) REFERENCES wine(wine_id) ON DELETE SET NULL;
+ALTER TABLE rating DROP CONSTRAINT IF EXISTS rating_game_id_fkey;
+ALTER TABLE rating ADD CONSTRAINT rating_game_id_fkey FOREIGN KEY (game_id) REFERENCES game(game_id) ON DELETE CASCADE;
+ALTER TABLE rating DROP CONSTRAINT IF EXISTS rating_participant_id_fkey;
+ALTER TABLE rating ADD CONSTRAINT rating_participant_id_fkey FOREIGN KEY (participant_id) REFERENCES participant(participant_id) ON DELETE CASCADE;
+ALTER TABLE rating DROP CONSTRAINT IF EXISTS rating_wine_id_fkey;
+ALTER TABLE rating ADD CONSTRAINT rating_wine_id_fkey FOREIGN KEY (wine_id) REFERENCES wine(wine_id) ON DELETE CASCADE;
+COMMIT;